@@ -0,0 +1,123 @@
+package analytics
+
+import (
+	"time"
+
+	"patrickwthomas.net/groupme-graph/database"
+)
+
+// Post is a message sent by a member, with only what activity statistics
+// need.
+type Post struct {
+	ID      string
+	GroupID string
+	UserID  string
+	At      int
+}
+
+// Heatmap counts messages by day of the week, starting on Monday, and hour of
+// the day.
+type Heatmap [7][24]int
+
+// Max is the largest count of the heatmap.
+func (h Heatmap) Max() int {
+	max := 0
+	for _, day := range h {
+		for _, count := range day {
+			if count > max {
+				max = count
+			}
+		}
+	}
+	return max
+}
+
+// NewHeatmap counts posts by day of the week and hour in a time zone.
+func NewHeatmap(posts []Post, loc *time.Location) Heatmap {
+	h := Heatmap{}
+	for _, p := range posts {
+		t := time.Unix(int64(p.At), 0).In(loc)
+		h[(int(t.Weekday())+6)%7][t.Hour()]++
+	}
+	return h
+}
+
+// Count is the number of posts in a window.
+type Count struct {
+	Window Window
+	Count  int
+}
+
+// CountPosts counts posts by the windows of a period, from the window of the
+// first post to the window of the last one. Empty windows are kept so that
+// gaps show in charts.
+func CountPosts(posts []Post, period string, loc *time.Location) ([]Count, error) {
+	if len(posts) == 0 {
+		return []Count{}, nil
+	}
+	first, last := posts[0].At, posts[0].At
+	byWindow := map[Window]int{}
+	for _, p := range posts {
+		w, err := WindowOf(period, p.At, loc)
+		if err != nil {
+			return nil, err
+		}
+		byWindow[w]++
+		if p.At < first {
+			first = p.At
+		}
+		if p.At > last {
+			last = p.At
+		}
+	}
+
+	counts := []Count{}
+	w, err := WindowOf(period, first, loc)
+	if err != nil {
+		return nil, err
+	}
+	end, _ := WindowOf(period, last, loc)
+	for {
+		counts = append(counts, Count{Window: w, Count: byWindow[w]})
+		if w == end {
+			break
+		}
+		w, _ = WindowOf(period, w.End, loc)
+	}
+	return counts, nil
+}
+
+// ByUser splits posts by their sender.
+func ByUser(posts []Post) map[string][]Post {
+	byUser := map[string][]Post{}
+	for _, p := range posts {
+		byUser[p.UserID] = append(byUser[p.UserID], p)
+	}
+	return byUser
+}
+
+// LoadPosts loads when the members of a group sent their messages, oldest
+// first, leaving out system messages.
+func LoadPosts(driver *database.Neo4j, groupID string) ([]Post, error) {
+	session, err := driver.NewReadSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	result, err := session.Run(`MATCH (m:Message{GroupID: $group})
+	WHERE coalesce(m.System, false) = false AND m.UserID <> "system"
+	RETURN m.ID, m.UserID, m.CreatedAt ORDER BY m.CreatedAt, m.ID`, map[string]interface{}{"group": groupID})
+	if err != nil {
+		return nil, err
+	}
+	posts := []Post{}
+	for result.Next() {
+		values := result.Record().Values()
+		id, _ := values[0].(string)
+		userID, _ := values[1].(string)
+		at, _ := values[2].(int64)
+		posts = append(posts, Post{ID: id, GroupID: groupID, UserID: userID, At: int(at)})
+	}
+	return posts, result.Err()
+}
@@ -0,0 +1,71 @@
+package analytics
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func at(year int, month time.Month, day, hour int) int {
+	return int(time.Date(year, month, day, hour, 0, 0, 0, time.UTC).Unix())
+}
+
+func TestNewHeatmap(t *testing.T) {
+	// 2021-03-01 is a Monday, 2021-03-07 a Sunday.
+	posts := []Post{{At: at(2021, 3, 1, 9)}, {At: at(2021, 3, 8, 9)}, {At: at(2021, 3, 7, 23)}}
+	h := NewHeatmap(posts, time.UTC)
+	if h[0][9] != 2 || h[6][23] != 1 || h.Max() != 2 {
+		t.Errorf("got %v", h)
+	}
+
+	// Sunday 23:00 UTC is Monday morning in Tokyo.
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip(err)
+	}
+	if h := NewHeatmap(posts[2:], tokyo); h[0][8] != 1 {
+		t.Errorf("got %v in Tokyo", h)
+	}
+}
+
+func TestCountPostsFillsGaps(t *testing.T) {
+	posts := []Post{{At: at(2021, 3, 5, 0)}, {At: at(2021, 1, 2, 0)}, {At: at(2021, 3, 9, 0)}}
+	counts, err := CountPosts(posts, PeriodMonth, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 3 || counts[0].Count != 1 || counts[1].Count != 0 || counts[2].Count != 2 {
+		t.Errorf("got %+v", counts)
+	}
+	if counts[1].Window.Label(PeriodMonth, time.UTC) != "2021-02" {
+		t.Errorf("got %+v", counts)
+	}
+}
+
+func TestRenderActivity(t *testing.T) {
+	posts := []Post{{GroupID: "1", UserID: "2", At: at(2021, 3, 1, 9)}}
+	h := NewHeatmap(posts, time.UTC)
+	counts, _ := CountPosts(posts, PeriodDay, time.UTC)
+
+	b := &bytes.Buffer{}
+	WriteHeatmap(b, h)
+	if lines := strings.Split(b.String(), "\n"); !strings.HasPrefix(lines[1], "Mon "+strings.Repeat(" ", 18)+"██") {
+		t.Errorf("got heatmap\n%s", b)
+	}
+
+	b.Reset()
+	w := csv.NewWriter(b)
+	WriteCountsCSV(w, "1", "2", counts, PeriodDay, time.UTC)
+	w.Flush()
+	if b.String() != "1,2,2021-03-01,1614556800,1\n" {
+		t.Errorf("got csv %q", b)
+	}
+
+	b.Reset()
+	WriteHeatmapSVG(b, "<Group>", h)
+	if !strings.HasPrefix(b.String(), "<svg") || !strings.Contains(b.String(), "&lt;Group&gt;") || strings.Count(b.String(), "<rect") != 7*24 {
+		t.Errorf("bad svg %s", b)
+	}
+}
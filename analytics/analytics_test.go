@@ -0,0 +1,174 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+// star is a graph of a centre talking back and forth with three leaves.
+func star() *Graph {
+	g := NewGraph()
+	for _, leaf := range []string{"a", "b", "c"} {
+		g.AddEdge("centre", leaf, 1)
+		g.AddEdge(leaf, "centre", 1)
+	}
+	return g
+}
+
+func TestBuildGraph(t *testing.T) {
+	g := BuildGraph([]Interaction{
+		{From: "1", To: "2", Count: 2},
+		{From: "1", To: "2"},
+		{From: "2", To: "2"},
+	})
+	if g.Len() != 2 || g.Weight("1", "2") != 3 || g.Weight("2", "1") != 0 || g.Weight("2", "2") != 0 {
+		t.Errorf("bad graph %+v", g)
+	}
+}
+
+func TestDegree(t *testing.T) {
+	degree := Degree(star())
+	if degree["centre"] != 6 || degree["a"] != 2 {
+		t.Errorf("got %v", degree)
+	}
+}
+
+func TestPageRank(t *testing.T) {
+	rank := PageRank(star(), 0.85)
+	sum := 0.0
+	for _, r := range rank {
+		sum += r
+	}
+	if !near(sum, 1) {
+		t.Errorf("ranks sum to %v", sum)
+	}
+	if rank["centre"] <= rank["a"] || !near(rank["a"], rank["b"]) {
+		t.Errorf("got %v", rank)
+	}
+
+	// A node without outgoing edges spreads its rank evenly.
+	g := NewGraph()
+	g.AddEdge("a", "b", 1)
+	rank = PageRank(g, 0.85)
+	if !near(rank["a"]+rank["b"], 1) || rank["b"] <= rank["a"] {
+		t.Errorf("got %v", rank)
+	}
+}
+
+func TestBetweenness(t *testing.T) {
+	betweenness := Betweenness(star())
+	if !near(betweenness["centre"], 1) || betweenness["a"] != 0 {
+		t.Errorf("got %v", betweenness)
+	}
+
+	// In the path a -> b -> c only b is between two others.
+	g := NewGraph()
+	g.AddEdge("a", "b", 1)
+	g.AddEdge("b", "c", 1)
+	betweenness = Betweenness(g)
+	if !near(betweenness["b"], 0.5) || betweenness["a"] != 0 || betweenness["c"] != 0 {
+		t.Errorf("got %v", betweenness)
+	}
+}
+
+func TestEigenvector(t *testing.T) {
+	eigenvector := Eigenvector(star())
+	// The leading eigenvector of a star with three leaves is (√3, 1, 1, 1)/√6.
+	if !near(eigenvector["centre"], math.Sqrt(3)/math.Sqrt(6)) || !near(eigenvector["a"], 1/math.Sqrt(6)) {
+		t.Errorf("got %v", eigenvector)
+	}
+}
+
+func TestCentralities(t *testing.T) {
+	centralities := Centralities(star())
+	if len(centralities) != 4 || centralities[0].UserID != "centre" || centralities[1].UserID != "a" {
+		t.Errorf("got %+v", centralities)
+	}
+	if len(Centralities(NewGraph())) != 0 {
+		t.Error("empty graph has centralities")
+	}
+}
+
+// cliques is two triangles joined by a single weak edge.
+func cliques() *Graph {
+	g := NewGraph()
+	for _, clique := range [][]string{{"a", "b", "c"}, {"x", "y", "z"}} {
+		for i, from := range clique {
+			for _, to := range clique[i+1:] {
+				g.AddEdge(from, to, 3)
+			}
+		}
+	}
+	g.AddEdge("c", "x", 1)
+	return g
+}
+
+func TestLouvain(t *testing.T) {
+	communities := Louvain(cliques())
+	labels := communities.Labels
+	if labels["a"] != labels["b"] || labels["a"] != labels["c"] || labels["x"] != labels["y"] || labels["x"] != labels["z"] || labels["a"] == labels["x"] {
+		t.Errorf("got %v", labels)
+	}
+	if communities.Modularity < 0.4 {
+		t.Errorf("modularity %v", communities.Modularity)
+	}
+	if members := communities.Members(); len(members) != 2 || len(members[0]) != 3 {
+		t.Errorf("got members %v", members)
+	}
+}
+
+func TestLouvainEmpty(t *testing.T) {
+	if len(Louvain(NewGraph()).Labels) != 0 {
+		t.Fail()
+	}
+	g := NewGraph()
+	g.AddNode("alone")
+	if c := Louvain(g); c.Labels["alone"] != 0 || c.Modularity != 0 {
+		t.Errorf("got %+v", c)
+	}
+}
+
+func TestSplitInteractions(t *testing.T) {
+	utc := time.UTC
+	jan := int(time.Date(2021, 1, 31, 23, 0, 0, 0, utc).Unix())
+	feb := int(time.Date(2021, 2, 1, 1, 0, 0, 0, utc).Unix())
+	windows, err := SplitInteractions([]Interaction{{At: feb}, {At: jan}, {At: feb}}, PeriodMonth, utc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(windows) != 2 || len(windows[0].Interactions) != 1 || len(windows[1].Interactions) != 2 {
+		t.Fatalf("got %+v", windows)
+	}
+	if label := windows[1].Window.Label(PeriodMonth, utc); label != "2021-02" {
+		t.Errorf("got label %s", label)
+	}
+
+	// In New York both are still in January.
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	windows, _ = SplitInteractions([]Interaction{{At: feb}, {At: jan}}, PeriodMonth, ny)
+	if len(windows) != 1 {
+		t.Errorf("got %+v", windows)
+	}
+}
+
+func TestWindowOfWeek(t *testing.T) {
+	sunday := int(time.Date(2021, 3, 7, 12, 0, 0, 0, time.UTC).Unix())
+	w, err := WindowOf(PeriodWeek, sunday, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Label(PeriodWeek, time.UTC) != "2021-03-01" || w.End-w.Start != 7*24*3600 {
+		t.Errorf("got %+v", w)
+	}
+	if _, err := WindowOf("fortnight", sunday, time.UTC); err == nil {
+		t.Error("unknown period accepted")
+	}
+}
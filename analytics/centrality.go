@@ -0,0 +1,209 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+)
+
+// Centrality is how central a member is to the interaction graph of a group.
+type Centrality struct {
+	UserID string `json:"user_id"`
+	// Degree is the total weight of the interactions of the member, given
+	// and received.
+	Degree      float64 `json:"degree"`
+	PageRank    float64 `json:"pagerank"`
+	Betweenness float64 `json:"betweenness"`
+	Eigenvector float64 `json:"eigenvector"`
+}
+
+// Centralities computes every centrality of the members of a graph, sorted
+// by PageRank.
+func Centralities(g *Graph) []Centrality {
+	degree := Degree(g)
+	pageRank := PageRank(g, 0.85)
+	betweenness := Betweenness(g)
+	eigenvector := Eigenvector(g)
+
+	results := make([]Centrality, 0, g.Len())
+	for _, id := range g.Nodes() {
+		results = append(results, Centrality{
+			UserID:      id,
+			Degree:      degree[id],
+			PageRank:    pageRank[id],
+			Betweenness: betweenness[id],
+			Eigenvector: eigenvector[id],
+		})
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].PageRank != results[j].PageRank {
+			return results[i].PageRank > results[j].PageRank
+		}
+		return results[i].UserID < results[j].UserID
+	})
+	return results
+}
+
+// Degree is the weighted degree of every node, counting edges in both
+// directions.
+func Degree(g *Graph) map[string]float64 {
+	degree := make([]float64, g.Len())
+	for f, edges := range g.out {
+		for t, w := range edges {
+			degree[f] += w
+			degree[t] += w
+		}
+	}
+	return g.scores(degree)
+}
+
+// Convergence settings of the iterative centralities.
+const (
+	maxIterations = 1000
+	tolerance     = 1e-10
+)
+
+// PageRank computes the weighted PageRank of every node. The rank of nodes
+// without outgoing edges is spread over every node.
+func PageRank(g *Graph, damping float64) map[string]float64 {
+	n := g.Len()
+	if n == 0 {
+		return map[string]float64{}
+	}
+	outWeight := make([]float64, n)
+	for f, edges := range g.out {
+		for _, w := range edges {
+			outWeight[f] += w
+		}
+	}
+
+	rank := make([]float64, n)
+	for i := range rank {
+		rank[i] = 1 / float64(n)
+	}
+	for iteration := 0; iteration < maxIterations; iteration++ {
+		dangling := 0.0
+		for i := range rank {
+			if outWeight[i] == 0 {
+				dangling += rank[i]
+			}
+		}
+		next := make([]float64, n)
+		for i := range next {
+			next[i] = (1-damping)/float64(n) + damping*dangling/float64(n)
+		}
+		for f, edges := range g.out {
+			for _, t := range sortedNeighbours(edges) {
+				next[t] += damping * rank[f] * edges[t] / outWeight[f]
+			}
+		}
+
+		change := 0.0
+		for i := range rank {
+			change += math.Abs(next[i] - rank[i])
+		}
+		rank = next
+		if change < tolerance {
+			break
+		}
+	}
+	return g.scores(rank)
+}
+
+// Betweenness computes the betweenness centrality of every node with Brandes'
+// algorithm, over shortest paths that ignore edge weights. Scores are
+// normalised by the number of pairs of other nodes.
+func Betweenness(g *Graph) map[string]float64 {
+	n := g.Len()
+	centrality := make([]float64, n)
+	for s := 0; s < n; s++ {
+		stack := []int{}
+		predecessors := make([][]int, n)
+		paths := make([]float64, n)
+		distance := make([]int, n)
+		for i := range distance {
+			distance[i] = -1
+		}
+		paths[s], distance[s] = 1, 0
+
+		queue := []int{s}
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+			stack = append(stack, v)
+			for _, w := range sortedNeighbours(g.out[v]) {
+				if distance[w] < 0 {
+					distance[w] = distance[v] + 1
+					queue = append(queue, w)
+				}
+				if distance[w] == distance[v]+1 {
+					paths[w] += paths[v]
+					predecessors[w] = append(predecessors[w], v)
+				}
+			}
+		}
+
+		dependency := make([]float64, n)
+		for i := len(stack) - 1; i >= 0; i-- {
+			w := stack[i]
+			for _, v := range predecessors[w] {
+				dependency[v] += paths[v] / paths[w] * (1 + dependency[w])
+			}
+			if w != s {
+				centrality[w] += dependency[w]
+			}
+		}
+	}
+
+	if n > 2 {
+		for i := range centrality {
+			centrality[i] /= float64((n - 1) * (n - 2))
+		}
+	}
+	return g.scores(centrality)
+}
+
+// Eigenvector computes the eigenvector centrality of every node, treating
+// the graph as undirected so that members who only receive interactions do
+// not drain the scores. Scores have a Euclidean norm of 1.
+func Eigenvector(g *Graph) map[string]float64 {
+	n := g.Len()
+	if n == 0 {
+		return map[string]float64{}
+	}
+	adjacency := g.undirected()
+
+	score := make([]float64, n)
+	for i := range score {
+		score[i] = 1 / math.Sqrt(float64(n))
+	}
+	for iteration := 0; iteration < maxIterations; iteration++ {
+		// Adding the previous score keeps the iteration from oscillating
+		// on bipartite graphs without changing the eigenvector.
+		next := make([]float64, n)
+		copy(next, score)
+		for v, edges := range adjacency {
+			for _, u := range sortedNeighbours(edges) {
+				next[v] += edges[u] * score[u]
+			}
+		}
+
+		norm := 0.0
+		for _, x := range next {
+			norm += x * x
+		}
+		norm = math.Sqrt(norm)
+		if norm == 0 {
+			break
+		}
+		change := 0.0
+		for i := range next {
+			next[i] /= norm
+			change += math.Abs(next[i] - score[i])
+		}
+		score = next
+		if change < tolerance {
+			break
+		}
+	}
+	return g.scores(score)
+}
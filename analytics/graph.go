@@ -0,0 +1,129 @@
+// Package analytics computes statistics about the members of groups from the
+// interactions between them: who liked, mentioned or replied to whom.
+package analytics
+
+import "sort"
+
+// Interaction kinds.
+const (
+	InteractionReaction = "reaction"
+	InteractionMention  = "mention"
+	InteractionReply    = "reply"
+)
+
+// Interaction is a member interacting with another one in a group, at the
+// time of the message that carried it.
+type Interaction struct {
+	GroupID string
+	From    string
+	To      string
+	Kind    string
+	At      int
+	Count   int
+}
+
+// Graph is a weighted directed graph of members.
+type Graph struct {
+	ids   []string
+	index map[string]int
+	out   []map[int]float64
+}
+
+// NewGraph creates an empty graph.
+func NewGraph() *Graph {
+	return &Graph{index: map[string]int{}}
+}
+
+// BuildGraph builds the interaction graph of a set of interactions, every
+// interaction adding its count to the weight of an edge. Members interacting
+// with themselves are left out.
+func BuildGraph(interactions []Interaction) *Graph {
+	g := NewGraph()
+	for _, i := range interactions {
+		if i.From == i.To {
+			continue
+		}
+		count := i.Count
+		if count == 0 {
+			count = 1
+		}
+		g.AddEdge(i.From, i.To, float64(count))
+	}
+	return g
+}
+
+// AddNode adds a node without edges, returning its index.
+func (g *Graph) AddNode(id string) int {
+	if i, ok := g.index[id]; ok {
+		return i
+	}
+	g.index[id] = len(g.ids)
+	g.ids = append(g.ids, id)
+	g.out = append(g.out, map[int]float64{})
+	return len(g.ids) - 1
+}
+
+// AddEdge adds weight to the edge between two nodes, adding them as needed.
+func (g *Graph) AddEdge(from, to string, weight float64) {
+	f, t := g.AddNode(from), g.AddNode(to)
+	g.out[f][t] += weight
+}
+
+// Nodes returns the IDs of the nodes, in the order they were added.
+func (g *Graph) Nodes() []string {
+	return g.ids
+}
+
+// Len is the number of nodes.
+func (g *Graph) Len() int {
+	return len(g.ids)
+}
+
+// Weight returns the weight of the edge between two nodes.
+func (g *Graph) Weight(from, to string) float64 {
+	f, ok := g.index[from]
+	if !ok {
+		return 0
+	}
+	t, ok := g.index[to]
+	if !ok {
+		return 0
+	}
+	return g.out[f][t]
+}
+
+// undirected returns the symmetric weights of the graph, adding the weights
+// in both directions.
+func (g *Graph) undirected() []map[int]float64 {
+	adjacency := make([]map[int]float64, g.Len())
+	for i := range adjacency {
+		adjacency[i] = map[int]float64{}
+	}
+	for f, edges := range g.out {
+		for t, w := range edges {
+			adjacency[f][t] += w
+			adjacency[t][f] += w
+		}
+	}
+	return adjacency
+}
+
+// sortedNeighbours returns the keys of an adjacency map in order, so results
+// do not depend on map iteration order.
+func sortedNeighbours(edges map[int]float64) []int {
+	neighbours := make([]int, 0, len(edges))
+	for n := range edges {
+		neighbours = append(neighbours, n)
+	}
+	sort.Ints(neighbours)
+	return neighbours
+}
+
+// scores maps node indexes back to IDs.
+func (g *Graph) scores(values []float64) map[string]float64 {
+	scores := make(map[string]float64, len(values))
+	for i, v := range values {
+		scores[g.ids[i]] = v
+	}
+	return scores
+}
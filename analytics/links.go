@@ -0,0 +1,97 @@
+package analytics
+
+import (
+	"sort"
+
+	"patrickwthomas.net/groupme-graph/database"
+)
+
+// Share is a link posted in a message of a group.
+type Share struct {
+	GroupID   string
+	UserID    string
+	MessageID string
+	URL       string
+	Domain    string
+}
+
+// DomainCount is how much a domain was shared in a group.
+type DomainCount struct {
+	Domain string
+	// Shares is the number of messages linking to the domain.
+	Shares int
+	// Links is the number of different links to the domain.
+	Links int
+	// Sharers is the number of members who shared them.
+	Sharers int
+}
+
+// Domains counts the shares of every domain by group, most shared first.
+func Domains(shares []Share) map[string][]DomainCount {
+	type key struct{ group, domain string }
+	messages := map[key]map[string]bool{}
+	links := map[key]map[string]bool{}
+	sharers := map[key]map[string]bool{}
+	for _, s := range shares {
+		k := key{s.GroupID, s.Domain}
+		if messages[k] == nil {
+			messages[k] = map[string]bool{}
+			links[k] = map[string]bool{}
+			sharers[k] = map[string]bool{}
+		}
+		messages[k][s.MessageID] = true
+		links[k][s.URL] = true
+		if s.UserID != "" {
+			sharers[k][s.UserID] = true
+		}
+	}
+
+	domains := map[string][]DomainCount{}
+	for k := range messages {
+		domains[k.group] = append(domains[k.group], DomainCount{
+			Domain:  k.domain,
+			Shares:  len(messages[k]),
+			Links:   len(links[k]),
+			Sharers: len(sharers[k]),
+		})
+	}
+	for _, counts := range domains {
+		sort.Slice(counts, func(i, j int) bool {
+			if counts[i].Shares != counts[j].Shares {
+				return counts[i].Shares > counts[j].Shares
+			}
+			return counts[i].Domain < counts[j].Domain
+		})
+	}
+	return domains
+}
+
+// LoadShares loads the links posted in messages, optionally only those of a
+// group.
+func LoadShares(driver *database.Neo4j, groupID string) ([]Share, error) {
+	session, err := driver.NewReadSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	result, err := session.Run(`MATCH (m:Message)-[:CONTAINS_LINK]->(l:Link)
+	WHERE $group = "" OR m.GroupID = $group
+	RETURN m.GroupID, CASE WHEN coalesce(m.System, false) THEN "" ELSE m.UserID END, m.ID, l.url, l.domain`,
+		map[string]interface{}{"group": groupID})
+	if err != nil {
+		return nil, err
+	}
+	shares := []Share{}
+	for result.Next() {
+		values := result.Record().Values()
+		s := Share{}
+		s.GroupID, _ = values[0].(string)
+		s.UserID, _ = values[1].(string)
+		s.MessageID, _ = values[2].(string)
+		s.URL, _ = values[3].(string)
+		s.Domain, _ = values[4].(string)
+		shares = append(shares, s)
+	}
+	return shares, result.Err()
+}
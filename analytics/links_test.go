@@ -0,0 +1,29 @@
+package analytics
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDomains(t *testing.T) {
+	shares := []Share{
+		{GroupID: "1", UserID: "a", MessageID: "m1", URL: "https://youtube.com/1", Domain: "youtube.com"},
+		{GroupID: "1", UserID: "b", MessageID: "m2", URL: "https://youtube.com/1", Domain: "youtube.com"},
+		{GroupID: "1", UserID: "b", MessageID: "m2", URL: "https://youtube.com/2", Domain: "youtube.com"},
+		{GroupID: "1", UserID: "a", MessageID: "m3", URL: "https://example.com", Domain: "example.com"},
+		{GroupID: "1", MessageID: "m4", URL: "https://b.org", Domain: "b.org"},
+		{GroupID: "2", UserID: "a", MessageID: "m5", URL: "https://example.com", Domain: "example.com"},
+	}
+	domains := Domains(shares)
+	want := []DomainCount{
+		{Domain: "youtube.com", Shares: 2, Links: 2, Sharers: 2},
+		{Domain: "b.org", Shares: 1, Links: 1, Sharers: 0},
+		{Domain: "example.com", Shares: 1, Links: 1, Sharers: 1},
+	}
+	if !reflect.DeepEqual(domains["1"], want) {
+		t.Errorf("got %+v", domains["1"])
+	}
+	if len(domains["2"]) != 1 {
+		t.Errorf("got %+v", domains["2"])
+	}
+}
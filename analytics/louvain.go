@@ -0,0 +1,207 @@
+package analytics
+
+import "sort"
+
+// Communities is a partition of the members of a graph.
+type Communities struct {
+	// Labels maps user IDs to their community. Communities are numbered from
+	// 0, largest first.
+	Labels     map[string]int
+	Modularity float64
+}
+
+// Members returns the members of every community.
+func (c Communities) Members() [][]string {
+	members := [][]string{}
+	for id, label := range c.Labels {
+		for len(members) <= label {
+			members = append(members, []string{})
+		}
+		members[label] = append(members[label], id)
+	}
+	for _, m := range members {
+		sort.Strings(m)
+	}
+	return members
+}
+
+// Louvain finds communities with the Louvain method: members are moved to
+// the community of a neighbour while that improves the modularity, then
+// communities are merged into single nodes and the process starts over until
+// nothing moves. Edge directions are ignored.
+func Louvain(g *Graph) Communities {
+	n := g.Len()
+	if n == 0 {
+		return Communities{Labels: map[string]int{}}
+	}
+
+	// labels tracks the community of every original node through the levels.
+	labels := make([]int, n)
+	for i := range labels {
+		labels[i] = i
+	}
+	adjacency := g.undirected()
+	for {
+		community, moved := louvainMoves(adjacency)
+		if !moved {
+			break
+		}
+		community = renumber(community)
+		for i := range labels {
+			labels[i] = community[labels[i]]
+		}
+		adjacency = aggregate(adjacency, community)
+	}
+
+	labels = bySize(labels)
+	return Communities{Labels: g.labels(labels), Modularity: modularity(g.undirected(), labels)}
+}
+
+// louvainMoves moves nodes between communities until the modularity stops
+// improving, reporting whether any node moved.
+func louvainMoves(adjacency []map[int]float64) ([]int, bool) {
+	n := len(adjacency)
+	community := make([]int, n)
+	degree := make([]float64, n)
+	total := make([]float64, n)
+	twoM := 0.0
+	for i, edges := range adjacency {
+		community[i] = i
+		for _, w := range edges {
+			degree[i] += w
+		}
+		total[i] = degree[i]
+		twoM += degree[i]
+	}
+	if twoM == 0 {
+		return community, false
+	}
+
+	movedAny := false
+	for moved := true; moved; {
+		moved = false
+		for i := 0; i < n; i++ {
+			// Weights from i to every neighbouring community.
+			links := map[int]float64{}
+			for _, j := range sortedNeighbours(adjacency[i]) {
+				if j != i {
+					links[community[j]] += adjacency[i][j]
+				}
+			}
+
+			current := community[i]
+			total[current] -= degree[i]
+			best, bestGain := current, links[current]-total[current]*degree[i]/twoM
+			candidates := make([]int, 0, len(links))
+			for c := range links {
+				candidates = append(candidates, c)
+			}
+			sort.Ints(candidates)
+			for _, c := range candidates {
+				gain := links[c] - total[c]*degree[i]/twoM
+				if gain > bestGain+1e-12 {
+					best, bestGain = c, gain
+				}
+			}
+			total[best] += degree[i]
+			if best != current {
+				community[i] = best
+				moved, movedAny = true, true
+			}
+		}
+	}
+	return community, movedAny
+}
+
+// aggregate merges the nodes of every community into a single node. Weights
+// within a community become a self loop.
+func aggregate(adjacency []map[int]float64, community []int) []map[int]float64 {
+	size := 0
+	for _, c := range community {
+		if c+1 > size {
+			size = c + 1
+		}
+	}
+	merged := make([]map[int]float64, size)
+	for i := range merged {
+		merged[i] = map[int]float64{}
+	}
+	for i, edges := range adjacency {
+		for j, w := range edges {
+			merged[community[i]][community[j]] += w
+		}
+	}
+	return merged
+}
+
+// renumber numbers communities from 0 in order of first appearance.
+func renumber(community []int) []int {
+	numbers := map[int]int{}
+	renumbered := make([]int, len(community))
+	for i, c := range community {
+		if _, ok := numbers[c]; !ok {
+			numbers[c] = len(numbers)
+		}
+		renumbered[i] = numbers[c]
+	}
+	return renumbered
+}
+
+// bySize numbers communities by decreasing size, ties in order of first
+// appearance.
+func bySize(labels []int) []int {
+	labels = renumber(labels)
+	sizes := map[int]int{}
+	for _, l := range labels {
+		sizes[l]++
+	}
+	order := make([]int, len(sizes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return sizes[order[i]] > sizes[order[j]]
+	})
+	rank := make([]int, len(order))
+	for r, l := range order {
+		rank[l] = r
+	}
+	sorted := make([]int, len(labels))
+	for i, l := range labels {
+		sorted[i] = rank[l]
+	}
+	return sorted
+}
+
+// modularity is the modularity of a partition of a symmetric graph.
+func modularity(adjacency []map[int]float64, labels []int) float64 {
+	inside := map[int]float64{}
+	total := map[int]float64{}
+	twoM := 0.0
+	for i, edges := range adjacency {
+		for j, w := range edges {
+			total[labels[i]] += w
+			twoM += w
+			if labels[i] == labels[j] {
+				inside[labels[i]] += w
+			}
+		}
+	}
+	if twoM == 0 {
+		return 0
+	}
+	q := 0.0
+	for c, t := range total {
+		q += inside[c]/twoM - (t/twoM)*(t/twoM)
+	}
+	return q
+}
+
+// labels maps node indexes back to IDs.
+func (g *Graph) labels(values []int) map[string]int {
+	labels := make(map[string]int, len(values))
+	for i, v := range values {
+		labels[g.ids[i]] = v
+	}
+	return labels
+}
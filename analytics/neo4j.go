@@ -0,0 +1,184 @@
+package analytics
+
+import (
+	"github.com/neo4j/neo4j-go-driver/neo4j"
+	"patrickwthomas.net/groupme-graph/database"
+)
+
+// interactionQueries find the interactions of a group by kind. Each returns
+// the acting user, the user acted upon and when.
+var interactionQueries = map[string]string{
+	InteractionReaction: `MATCH (msg:Message{GroupID: $group})<-[:REACTED]-(m:Member)
+	RETURN m.UserID, msg.UserID, msg.CreatedAt`,
+	InteractionMention: `MATCH (msg:Message{GroupID: $group})-[:HAS_ATTACHMENT]->(a:Attachment{Type: "mentions"})
+	UNWIND a.UserIDs AS target
+	RETURN msg.UserID, target, msg.CreatedAt`,
+	InteractionReply: `MATCH (msg:Message{GroupID: $group})-[:HAS_ATTACHMENT]->(a:Attachment{Type: "reply"})
+	MATCH (original:Message{ID: a.ReplyID})
+	RETURN msg.UserID, original.UserID, msg.CreatedAt`,
+}
+
+// interactionKinds is the order interactions are loaded in.
+var interactionKinds = []string{InteractionReaction, InteractionMention, InteractionReply}
+
+// GroupInfo names a group.
+type GroupInfo struct {
+	ID   string
+	Name string
+}
+
+// LoadGroups lists the groups in the database, optionally only the one with
+// the given ID.
+func LoadGroups(driver *database.Neo4j, groupID string) ([]GroupInfo, error) {
+	session, err := driver.NewReadSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	result, err := session.Run(`MATCH (g:Group) WHERE $group = "" OR g.ID = $group
+	RETURN g.ID, g.Name ORDER BY g.Name`, map[string]interface{}{"group": groupID})
+	if err != nil {
+		return nil, err
+	}
+	groups := []GroupInfo{}
+	for result.Next() {
+		values := result.Record().Values()
+		group := GroupInfo{}
+		group.ID, _ = values[0].(string)
+		group.Name, _ = values[1].(string)
+		groups = append(groups, group)
+	}
+	return groups, result.Err()
+}
+
+// LoadInteractions loads every interaction between members of a group.
+func LoadInteractions(driver *database.Neo4j, groupID string) ([]Interaction, error) {
+	session, err := driver.NewReadSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	interactions := []Interaction{}
+	for _, kind := range interactionKinds {
+		result, err := session.Run(interactionQueries[kind], map[string]interface{}{"group": groupID})
+		if err != nil {
+			return nil, err
+		}
+		for result.Next() {
+			values := result.Record().Values()
+			from, _ := values[0].(string)
+			to, _ := values[1].(string)
+			at, _ := values[2].(int64)
+			if from == "" || to == "" || from == "system" || to == "system" {
+				continue
+			}
+			interactions = append(interactions, Interaction{GroupID: groupID, From: from, To: to, Kind: kind, At: int(at), Count: 1})
+		}
+		if result.Err() != nil {
+			return nil, result.Err()
+		}
+	}
+	return interactions, nil
+}
+
+// LoadMemberNames returns the names of the members of a group: their
+// nickname in the group, or else their latest nickname anywhere.
+func LoadMemberNames(driver *database.Neo4j, groupID string) (map[string]string, error) {
+	session, err := driver.NewReadSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	result, err := session.Run(`MATCH (m:Member)
+	OPTIONAL MATCH (m)-[r:MEMBER_OF]->(:Group{ID: $group})
+	RETURN m.UserID, coalesce(r.nickname, m.Nickname)`, map[string]interface{}{"group": groupID})
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]string{}
+	for result.Next() {
+		values := result.Record().Values()
+		userID, _ := values[0].(string)
+		name, _ := values[1].(string)
+		names[userID] = name
+	}
+	return names, result.Err()
+}
+
+const saveCentralityQuery = `UNWIND $rows AS c
+MERGE (n:Centrality{UserID: c.UserID, GroupID: $group})
+SET n.degree = c.degree, n.pagerank = c.pagerank, n.betweenness = c.betweenness,
+	n.eigenvector = c.eigenvector, n.computed_at = $at
+WITH n, c
+MATCH (m:Member{UserID: c.UserID}), (g:Group{ID: $group})
+MERGE (m)-[:HAS_CENTRALITY]->(n)
+MERGE (n)-[:IN_GROUP]->(g)`
+
+// SaveCentralities writes the centralities of the members of a group into
+// (:Member)-[:HAS_CENTRALITY]->(:Centrality)-[:IN_GROUP]->(:Group) nodes.
+func SaveCentralities(driver *database.Neo4j, groupID string, centralities []Centrality, at int) error {
+	session, err := driver.NewWriteSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	rows := make([]interface{}, len(centralities))
+	for i, c := range centralities {
+		rows[i] = map[string]interface{}{
+			"UserID":      c.UserID,
+			"degree":      c.Degree,
+			"pagerank":    c.PageRank,
+			"betweenness": c.Betweenness,
+			"eigenvector": c.Eigenvector,
+		}
+	}
+
+	_, err = session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(saveCentralityQuery, map[string]interface{}{"rows": rows, "group": groupID, "at": at})
+		if err != nil {
+			return nil, err
+		}
+		return result.Consume()
+	})
+	return err
+}
+
+const saveCommunitiesQuery = `UNWIND $rows AS c
+MATCH (m:Member{UserID: c.UserID}), (g:Group{ID: $group})
+MERGE (m)-[r:IN_COMMUNITY{period: $period, window_start: $start}]->(g)
+SET r.window_end = $end, r.community = c.community, r.computed_at = $at`
+
+// SaveCommunities writes the communities of the members of a group during a
+// window into (:Member)-[:IN_COMMUNITY {community}]->(:Group) edges.
+func SaveCommunities(driver *database.Neo4j, groupID, period string, window Window, communities Communities, at int) error {
+	session, err := driver.NewWriteSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	rows := []interface{}{}
+	for userID, label := range communities.Labels {
+		rows = append(rows, map[string]interface{}{"UserID": userID, "community": label})
+	}
+
+	_, err = session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(saveCommunitiesQuery, map[string]interface{}{
+			"rows":   rows,
+			"group":  groupID,
+			"period": period,
+			"start":  window.Start,
+			"end":    window.End,
+			"at":     at,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return result.Consume()
+	})
+	return err
+}
@@ -0,0 +1,216 @@
+package analytics
+
+import (
+	"sort"
+
+	"github.com/neo4j/neo4j-go-driver/neo4j"
+	"patrickwthomas.net/groupme-graph/database"
+)
+
+// Rosters are the members of groups, by group ID then user ID.
+type Rosters map[string]map[string]bool
+
+// add adds a member to a group.
+func (r Rosters) add(groupID, userID string) {
+	if r[groupID] == nil {
+		r[groupID] = map[string]bool{}
+	}
+	r[groupID][userID] = true
+}
+
+// Overlap is how much two groups share members. A is before B.
+type Overlap struct {
+	A       string
+	B       string
+	Shared  int
+	Jaccard float64
+	// SharedPosters and PosterJaccard only count active posters.
+	SharedPosters int
+	PosterJaccard float64
+}
+
+// Overlaps computes the overlap of every pair of groups sharing members,
+// most shared members first.
+func Overlaps(members, posters Rosters) []Overlap {
+	groups := []string{}
+	for id := range members {
+		groups = append(groups, id)
+	}
+	sort.Strings(groups)
+
+	overlaps := []Overlap{}
+	for i, a := range groups {
+		for _, b := range groups[i+1:] {
+			o := Overlap{A: a, B: b}
+			o.Shared, o.Jaccard = jaccard(members[a], members[b])
+			if o.Shared == 0 {
+				continue
+			}
+			o.SharedPosters, o.PosterJaccard = jaccard(posters[a], posters[b])
+			overlaps = append(overlaps, o)
+		}
+	}
+	sort.SliceStable(overlaps, func(i, j int) bool {
+		if overlaps[i].Shared != overlaps[j].Shared {
+			return overlaps[i].Shared > overlaps[j].Shared
+		}
+		return overlaps[i].Jaccard > overlaps[j].Jaccard
+	})
+	return overlaps
+}
+
+// jaccard returns the size of the intersection of two sets and their Jaccard
+// index.
+func jaccard(a, b map[string]bool) (int, float64) {
+	shared := 0
+	for id := range a {
+		if b[id] {
+			shared++
+		}
+	}
+	union := len(a) + len(b) - shared
+	if union == 0 {
+		return 0, 0
+	}
+	return shared, float64(shared) / float64(union)
+}
+
+// Bridge is a member who is the only one linking some pairs of groups.
+type Bridge struct {
+	UserID string
+	// Groups are the groups of the member.
+	Groups []string
+	// Links are the pairs of groups the member is the only one to share.
+	Links [][2]string
+}
+
+// Bridges finds the members who connect otherwise separate groups, most links
+// first.
+func Bridges(members Rosters) []Bridge {
+	groupsOf := map[string][]string{}
+	for groupID, roster := range members {
+		for userID := range roster {
+			groupsOf[userID] = append(groupsOf[userID], groupID)
+		}
+	}
+	shared := map[[2]string]int{}
+	for _, groups := range groupsOf {
+		sort.Strings(groups)
+		for i, a := range groups {
+			for _, b := range groups[i+1:] {
+				shared[[2]string{a, b}]++
+			}
+		}
+	}
+
+	bridges := []Bridge{}
+	for userID, groups := range groupsOf {
+		b := Bridge{UserID: userID, Groups: groups}
+		for i, g := range groups {
+			for _, h := range groups[i+1:] {
+				if shared[[2]string{g, h}] == 1 {
+					b.Links = append(b.Links, [2]string{g, h})
+				}
+			}
+		}
+		if len(b.Links) > 0 {
+			bridges = append(bridges, b)
+		}
+	}
+	sort.Slice(bridges, func(i, j int) bool {
+		if len(bridges[i].Links) != len(bridges[j].Links) {
+			return len(bridges[i].Links) > len(bridges[j].Links)
+		}
+		return bridges[i].UserID < bridges[j].UserID
+	})
+	return bridges
+}
+
+// LoadRosters loads the current members of every group, and the members who
+// sent at least minPosts messages to it.
+func LoadRosters(driver *database.Neo4j, minPosts int) (Rosters, Rosters, error) {
+	session, err := driver.NewReadSession()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer session.Close()
+
+	members := Rosters{}
+	result, err := session.Run(`MATCH (m:Member)-[r:MEMBER_OF]->(g:Group) WHERE r.left_at IS NULL
+	RETURN g.ID, m.UserID`, map[string]interface{}{})
+	if err != nil {
+		return nil, nil, err
+	}
+	for result.Next() {
+		values := result.Record().Values()
+		groupID, _ := values[0].(string)
+		userID, _ := values[1].(string)
+		members.add(groupID, userID)
+	}
+	if result.Err() != nil {
+		return nil, nil, result.Err()
+	}
+
+	posters := Rosters{}
+	result, err = session.Run(`MATCH (m:Message) WHERE coalesce(m.System, false) = false AND m.UserID <> "system"
+	WITH m.GroupID AS group, m.UserID AS user, count(*) AS posts WHERE posts >= $min
+	RETURN group, user`, map[string]interface{}{"min": minPosts})
+	if err != nil {
+		return nil, nil, err
+	}
+	for result.Next() {
+		values := result.Record().Values()
+		groupID, _ := values[0].(string)
+		userID, _ := values[1].(string)
+		posters.add(groupID, userID)
+	}
+	return members, posters, result.Err()
+}
+
+// Queries replacing the SHARES_MEMBERS projection.
+const (
+	clearOverlapsQuery = `MATCH (:Group)-[r:SHARES_MEMBERS]->(:Group) DELETE r`
+
+	saveOverlapsQuery = `UNWIND $rows AS o
+MATCH (a:Group{ID: o.A}), (b:Group{ID: o.B})
+MERGE (a)-[r:SHARES_MEMBERS]->(b)
+SET r.count = o.count, r.jaccard = o.jaccard, r.shared_posters = o.shared_posters, r.poster_jaccard = o.poster_jaccard`
+)
+
+// SaveOverlaps replaces the (:Group)-[:SHARES_MEMBERS]->(:Group) edges.
+func SaveOverlaps(driver *database.Neo4j, overlaps []Overlap) error {
+	session, err := driver.NewWriteSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	rows := make([]interface{}, len(overlaps))
+	for i, o := range overlaps {
+		rows[i] = map[string]interface{}{
+			"A":              o.A,
+			"B":              o.B,
+			"count":          o.Shared,
+			"jaccard":        o.Jaccard,
+			"shared_posters": o.SharedPosters,
+			"poster_jaccard": o.PosterJaccard,
+		}
+	}
+
+	_, err = session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(clearOverlapsQuery, map[string]interface{}{})
+		if err != nil {
+			return nil, err
+		}
+		_, err = result.Consume()
+		if err != nil {
+			return nil, err
+		}
+		result, err = tx.Run(saveOverlapsQuery, map[string]interface{}{"rows": rows})
+		if err != nil {
+			return nil, err
+		}
+		return result.Consume()
+	})
+	return err
+}
@@ -0,0 +1,51 @@
+package analytics
+
+import (
+	"reflect"
+	"testing"
+)
+
+func rosters(groups map[string][]string) Rosters {
+	r := Rosters{}
+	for groupID, users := range groups {
+		for _, userID := range users {
+			r.add(groupID, userID)
+		}
+	}
+	return r
+}
+
+func TestOverlaps(t *testing.T) {
+	members := rosters(map[string][]string{
+		"1": {"a", "b", "c"},
+		"2": {"b", "c", "d"},
+		"3": {"x", "c"},
+		"4": {"y"},
+	})
+	posters := rosters(map[string][]string{"1": {"b"}, "2": {"b", "d"}})
+
+	overlaps := Overlaps(members, posters)
+	if len(overlaps) != 3 {
+		t.Fatalf("got %+v", overlaps)
+	}
+	first := overlaps[0]
+	if first.A != "1" || first.B != "2" || first.Shared != 2 || first.Jaccard != 0.5 || first.SharedPosters != 1 || first.PosterJaccard != 0.5 {
+		t.Errorf("got %+v", first)
+	}
+}
+
+func TestBridges(t *testing.T) {
+	members := rosters(map[string][]string{
+		"1": {"a", "b", "c"},
+		"2": {"b", "c", "d"},
+		"3": {"c", "x"},
+	})
+
+	bridges := Bridges(members)
+	if len(bridges) != 1 || bridges[0].UserID != "c" {
+		t.Fatalf("got %+v", bridges)
+	}
+	if !reflect.DeepEqual(bridges[0].Links, [][2]string{{"1", "3"}, {"2", "3"}}) {
+		t.Errorf("got links %v", bridges[0].Links)
+	}
+}
@@ -0,0 +1,151 @@
+package analytics
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+)
+
+// Weekdays are the rows of a heatmap.
+var Weekdays = [7]string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+
+// shades go from no messages to the most messages of a heatmap.
+var shades = []string{" ", "░", "▒", "▓", "█"}
+
+// WriteHeatmap draws a heatmap for a terminal, two characters per hour.
+func WriteHeatmap(w io.Writer, h Heatmap) {
+	max := h.Max()
+	fmt.Fprint(w, "    ")
+	for hour := 0; hour < 24; hour += 3 {
+		fmt.Fprintf(w, "%-6d", hour)
+	}
+	fmt.Fprintln(w)
+	for day, counts := range h {
+		fmt.Fprintf(w, "%s ", Weekdays[day])
+		for _, count := range counts {
+			shade := shades[0]
+			if count > 0 {
+				shade = shades[1+(count*(len(shades)-1)-1)/max]
+			}
+			fmt.Fprint(w, shade+shade)
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "    █ = %d messages\n", max)
+}
+
+// barWidth is the width of the longest bar drawn for a terminal.
+const barWidth = 50
+
+// WriteCounts draws counts as horizontal bars for a terminal.
+func WriteCounts(w io.Writer, counts []Count, period string, loc *time.Location) {
+	max := 0
+	for _, c := range counts {
+		if c.Count > max {
+			max = c.Count
+		}
+	}
+	for _, c := range counts {
+		bar := 0
+		if max > 0 {
+			bar = c.Count * barWidth / max
+		}
+		fmt.Fprintf(w, "%-10s %s %d\n", c.Window.Label(period, loc), strings.Repeat("█", bar), c.Count)
+	}
+}
+
+// HeatmapCSVHeader is the header of WriteHeatmapCSV rows.
+var HeatmapCSVHeader = []string{"group", "member", "weekday", "hour", "messages"}
+
+// WriteHeatmapCSV writes a row per hour of the week.
+func WriteHeatmapCSV(w *csv.Writer, groupID, userID string, h Heatmap) error {
+	for day, counts := range h {
+		for hour, count := range counts {
+			err := w.Write([]string{groupID, userID, Weekdays[day], fmt.Sprint(hour), fmt.Sprint(count)})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CountsCSVHeader is the header of WriteCountsCSV rows.
+var CountsCSVHeader = []string{"group", "member", "window", "start", "messages"}
+
+// WriteCountsCSV writes a row per window.
+func WriteCountsCSV(w *csv.Writer, groupID, userID string, counts []Count, period string, loc *time.Location) error {
+	for _, c := range counts {
+		err := w.Write([]string{groupID, userID, c.Window.Label(period, loc), fmt.Sprint(c.Window.Start), fmt.Sprint(c.Count)})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Layout of the SVG charts.
+const (
+	svgCell   = 24
+	svgMargin = 48
+	svgHeight = 240
+)
+
+// WriteHeatmapSVG draws a heatmap as an SVG image.
+func WriteHeatmapSVG(w io.Writer, title string, h Heatmap) {
+	max := h.Max()
+	width, height := svgMargin+24*svgCell+svgMargin/2, svgMargin+7*svgCell+svgMargin/2
+	fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="11">`+"\n", width, height)
+	fmt.Fprintf(w, `<text x="%d" y="18" font-size="14">%s</text>`+"\n", svgMargin, html.EscapeString(title))
+	for hour := 0; hour < 24; hour++ {
+		fmt.Fprintf(w, `<text x="%d" y="%d" text-anchor="middle">%d</text>`+"\n", svgMargin+hour*svgCell+svgCell/2, svgMargin-6, hour)
+	}
+	for day, counts := range h {
+		y := svgMargin + day*svgCell
+		fmt.Fprintf(w, `<text x="%d" y="%d" text-anchor="end">%s</text>`+"\n", svgMargin-6, y+svgCell/2+4, Weekdays[day])
+		for hour, count := range counts {
+			opacity := 0.0
+			if max > 0 {
+				opacity = float64(count) / float64(max)
+			}
+			fmt.Fprintf(w, `<rect x="%d" y="%d" width="%d" height="%d" fill="#1f6feb" fill-opacity="%.3f" stroke="#ddd"><title>%s %d:00: %d</title></rect>`+"\n",
+				svgMargin+hour*svgCell, y, svgCell, svgCell, opacity, Weekdays[day], hour, count)
+		}
+	}
+	fmt.Fprintln(w, "</svg>")
+}
+
+// WriteCountsSVG draws counts as an SVG bar chart.
+func WriteCountsSVG(w io.Writer, title string, counts []Count, period string, loc *time.Location) {
+	max := 0
+	for _, c := range counts {
+		if c.Count > max {
+			max = c.Count
+		}
+	}
+	bar := 12
+	if len(counts) > 60 {
+		bar = 4
+	}
+	width, height := svgMargin+len(counts)*bar+svgMargin/2, svgMargin+svgHeight+svgMargin
+	fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="11">`+"\n", width, height)
+	fmt.Fprintf(w, `<text x="%d" y="18" font-size="14">%s</text>`+"\n", svgMargin, html.EscapeString(title))
+	fmt.Fprintf(w, `<text x="%d" y="%d" text-anchor="end">%d</text>`+"\n", svgMargin-6, svgMargin+4, max)
+	fmt.Fprintf(w, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#999"/>`+"\n", svgMargin, svgMargin+svgHeight, width-svgMargin/2, svgMargin+svgHeight)
+	for i, c := range counts {
+		h := 0
+		if max > 0 {
+			h = c.Count * svgHeight / max
+		}
+		label := c.Window.Label(period, loc)
+		fmt.Fprintf(w, `<rect x="%d" y="%d" width="%d" height="%d" fill="#1f6feb"><title>%s: %d</title></rect>`+"\n",
+			svgMargin+i*bar+1, svgMargin+svgHeight-h, bar-2, h, label, c.Count)
+		if i == 0 || i == len(counts)-1 {
+			fmt.Fprintf(w, `<text x="%d" y="%d" text-anchor="middle">%s</text>`+"\n", svgMargin+i*bar+bar/2, svgMargin+svgHeight+16, label)
+		}
+	}
+	fmt.Fprintln(w, "</svg>")
+}
@@ -0,0 +1,200 @@
+package analytics
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/neo4j"
+	"patrickwthomas.net/groupme-graph/database"
+)
+
+// DefaultSessionGap is the silence after which a new conversation starts.
+const DefaultSessionGap = 30 * time.Minute
+
+// Session is a conversation: messages of a group with no silence longer than
+// the gap between them.
+type Session struct {
+	ID      string
+	GroupID string
+	Start   int
+	End     int
+	// Posts are the messages of the session, oldest first.
+	Posts []Post
+}
+
+// Starter is the member who sent the first message of the session.
+func (s Session) Starter() string {
+	return s.Posts[0].UserID
+}
+
+// Ender is the member who sent the last message of the session.
+func (s Session) Ender() string {
+	return s.Posts[len(s.Posts)-1].UserID
+}
+
+// Participants is the number of members who took part in the session.
+func (s Session) Participants() int {
+	users := map[string]bool{}
+	for _, p := range s.Posts {
+		users[p.UserID] = true
+	}
+	return len(users)
+}
+
+// Segment splits the posts of a group into sessions wherever more than gap
+// passes between two messages.
+func Segment(posts []Post, gap time.Duration) []Session {
+	sorted := make([]Post, len(posts))
+	copy(sorted, posts)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].At < sorted[j].At
+	})
+
+	sessions := []Session{}
+	for i, p := range sorted {
+		if i == 0 || time.Duration(p.At-sorted[i-1].At)*time.Second > gap {
+			sessions = append(sessions, Session{ID: fmt.Sprintf("%s:%s", p.GroupID, p.ID), GroupID: p.GroupID, Start: p.At})
+		}
+		s := &sessions[len(sessions)-1]
+		s.Posts = append(s.Posts, p)
+		s.End = p.At
+	}
+	return sessions
+}
+
+// Turns counts how many sessions every member started and ended.
+type Turns struct {
+	Started map[string]int
+	Ended   map[string]int
+}
+
+// CountTurns counts the starters and enders of sessions. Sessions of a single
+// message are left out, since nobody answered.
+func CountTurns(sessions []Session) Turns {
+	t := Turns{Started: map[string]int{}, Ended: map[string]int{}}
+	for _, s := range sessions {
+		if len(s.Posts) < 2 {
+			continue
+		}
+		t.Started[s.Starter()]++
+		t.Ended[s.Ender()]++
+	}
+	return t
+}
+
+// Latency is how fast a member answers another one.
+type Latency struct {
+	// From is the member answered, To the one answering.
+	From string
+	To   string
+	// Median is the median response time in seconds.
+	Median  float64
+	Answers int
+}
+
+// ResponseLatencies computes the median time members take to answer each
+// other within sessions: whenever the sender changes, the new sender answers
+// the previous one. Pairs are sorted by number of answers.
+func ResponseLatencies(sessions []Session) []Latency {
+	type pair struct{ from, to string }
+	delays := map[pair][]int{}
+	for _, s := range sessions {
+		for i := 1; i < len(s.Posts); i++ {
+			previous, p := s.Posts[i-1], s.Posts[i]
+			if p.UserID != previous.UserID {
+				key := pair{previous.UserID, p.UserID}
+				delays[key] = append(delays[key], p.At-previous.At)
+			}
+		}
+	}
+
+	latencies := []Latency{}
+	for key, d := range delays {
+		latencies = append(latencies, Latency{From: key.from, To: key.to, Median: median(d), Answers: len(d)})
+	}
+	sort.Slice(latencies, func(i, j int) bool {
+		if latencies[i].Answers != latencies[j].Answers {
+			return latencies[i].Answers > latencies[j].Answers
+		}
+		if latencies[i].From != latencies[j].From {
+			return latencies[i].From < latencies[j].From
+		}
+		return latencies[i].To < latencies[j].To
+	})
+	return latencies
+}
+
+func median(values []int) float64 {
+	sorted := make([]int, len(values))
+	copy(sorted, values)
+	sort.Ints(sorted)
+	n := len(sorted)
+	if n == 0 {
+		return 0
+	} else if n%2 == 1 {
+		return float64(sorted[n/2])
+	}
+	return float64(sorted[n/2-1]+sorted[n/2]) / 2
+}
+
+// Queries replacing the sessions of a group.
+const (
+	clearSessionsQuery = `MATCH (s:Session{GroupID: $group}) DETACH DELETE s`
+
+	saveSessionsQuery = `UNWIND $rows AS s
+CREATE (n:Session{ID: s.ID, GroupID: $group, start: s.start, end: s.end, messages: s.messages,
+	participants: s.participants, starter: s.starter, ender: s.ender, gap: $gap})
+WITH n, s
+MATCH (g:Group{ID: $group})
+MERGE (g)-[:HAS_SESSION]->(n)
+WITH n, s
+UNWIND s.message_ids AS id
+MATCH (m:Message{ID: id})
+MERGE (n)-[:CONTAINS]->(m)`
+)
+
+// SaveSessions replaces the sessions of a group with
+// (:Group)-[:HAS_SESSION]->(:Session)-[:CONTAINS]->(:Message) nodes.
+func SaveSessions(driver *database.Neo4j, groupID string, sessions []Session, gap time.Duration) error {
+	session, err := driver.NewWriteSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	rows := make([]interface{}, len(sessions))
+	for i, s := range sessions {
+		ids := make([]string, len(s.Posts))
+		for j, p := range s.Posts {
+			ids[j] = p.ID
+		}
+		rows[i] = map[string]interface{}{
+			"ID":           s.ID,
+			"start":        s.Start,
+			"end":          s.End,
+			"messages":     len(s.Posts),
+			"participants": s.Participants(),
+			"starter":      s.Starter(),
+			"ender":        s.Ender(),
+			"message_ids":  ids,
+		}
+	}
+
+	_, err = session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(clearSessionsQuery, map[string]interface{}{"group": groupID})
+		if err != nil {
+			return nil, err
+		}
+		_, err = result.Consume()
+		if err != nil {
+			return nil, err
+		}
+		result, err = tx.Run(saveSessionsQuery, map[string]interface{}{"rows": rows, "group": groupID, "gap": int(gap.Seconds())})
+		if err != nil {
+			return nil, err
+		}
+		return result.Consume()
+	})
+	return err
+}
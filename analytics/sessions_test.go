@@ -0,0 +1,48 @@
+package analytics
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func posts(senders string, at ...int) []Post {
+	posts := []Post{}
+	for i, s := range senders {
+		posts = append(posts, Post{ID: fmt.Sprint(i + 1), GroupID: "g", UserID: string(s), At: at[i]})
+	}
+	return posts
+}
+
+func TestSegment(t *testing.T) {
+	p := posts("ababcca", 0, 60, 120, 300, 5000, 9000, 9030)
+	sessions := Segment(p, 30*time.Minute)
+	if len(sessions) != 3 {
+		t.Fatalf("got %d sessions", len(sessions))
+	}
+	first := sessions[0]
+	if first.ID != "g:1" || len(first.Posts) != 4 || first.Start != 0 || first.End != 300 || first.Starter() != "a" || first.Ender() != "b" || first.Participants() != 2 {
+		t.Errorf("bad session %+v", first)
+	}
+
+	turns := CountTurns(sessions)
+	if turns.Started["a"] != 1 || turns.Started["c"] != 1 || turns.Ended["a"] != 1 || turns.Ended["b"] != 1 || turns.Started["b"] != 0 {
+		t.Errorf("got %+v", turns)
+	}
+}
+
+func TestResponseLatencies(t *testing.T) {
+	p := posts("ababab", 0, 60, 80, 380, 5000, 5010)
+	latencies := ResponseLatencies(Segment(p, 30*time.Minute))
+	if len(latencies) != 2 {
+		t.Fatalf("got %+v", latencies)
+	}
+	// b answered a after 60s, 300s and 10s, a answered b after 20s.
+	ab := latencies[0]
+	if ab.From != "a" || ab.To != "b" || ab.Answers != 3 || ab.Median != 60 {
+		t.Errorf("got %+v", ab)
+	}
+	if latencies[1].From != "b" || latencies[1].Median != 20 {
+		t.Errorf("got %+v", latencies[1])
+	}
+}
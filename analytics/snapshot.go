@@ -0,0 +1,238 @@
+package analytics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/neo4j"
+	"patrickwthomas.net/groupme-graph/database"
+)
+
+// Edge is a weighted edge of an interaction graph.
+type Edge struct {
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Weight float64 `json:"weight"`
+}
+
+// Edges returns the edges of the graph, sorted by their nodes.
+func (g *Graph) Edges() []Edge {
+	edges := []Edge{}
+	for f, out := range g.out {
+		for t, w := range out {
+			edges = append(edges, Edge{From: g.ids[f], To: g.ids[t], Weight: w})
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+	return edges
+}
+
+// Snapshot is the interaction graph of a group during a window, with the
+// centralities of its members.
+type Snapshot struct {
+	GroupID      string       `json:"group_id"`
+	Period       string       `json:"period"`
+	Label        string       `json:"label"`
+	Window       Window       `json:"window"`
+	Edges        []Edge       `json:"edges"`
+	Centralities []Centrality `json:"centralities"`
+}
+
+// ID identifies the snapshot among the snapshots of every group.
+func (s Snapshot) ID() string {
+	return fmt.Sprintf("%s:%s:%d", s.GroupID, s.Period, s.Window.Start)
+}
+
+// Snapshots builds a snapshot of a group for every window of a period with
+// interactions, oldest first.
+func Snapshots(groupID string, interactions []Interaction, period string, loc *time.Location) ([]Snapshot, error) {
+	windows, err := SplitInteractions(interactions, period, loc)
+	if err != nil {
+		return nil, err
+	}
+	snapshots := make([]Snapshot, len(windows))
+	for i, w := range windows {
+		g := BuildGraph(w.Interactions)
+		snapshots[i] = Snapshot{
+			GroupID:      groupID,
+			Period:       period,
+			Label:        w.Window.Label(period, loc),
+			Window:       w.Window,
+			Edges:        g.Edges(),
+			Centralities: Centralities(g),
+		}
+	}
+	return snapshots, nil
+}
+
+// WriteSnapshot writes a snapshot as JSON.
+func WriteSnapshot(w io.Writer, s Snapshot) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(s)
+}
+
+// ReadSnapshot reads a snapshot written by WriteSnapshot.
+func ReadSnapshot(r io.Reader) (Snapshot, error) {
+	s := Snapshot{}
+	err := json.NewDecoder(r).Decode(&s)
+	return s, err
+}
+
+// CentralityChange is how the centrality of a member changed between two
+// snapshots. Members missing from a snapshot have zero scores in it.
+type CentralityChange struct {
+	UserID string
+	Before Centrality
+	After  Centrality
+}
+
+// SnapshotDiff is what changed between two snapshots.
+type SnapshotDiff struct {
+	// New and Lost are the edges only found in the later and earlier
+	// snapshot. Changed edges are in both, with the weight difference.
+	New     []Edge
+	Lost    []Edge
+	Changed []Edge
+	// Centralities are sorted by the largest PageRank change first.
+	Centralities []CentralityChange
+}
+
+// Diff compares an earlier snapshot with a later one.
+func Diff(before, after Snapshot) SnapshotDiff {
+	type key struct{ from, to string }
+	weights := map[key]float64{}
+	for _, e := range before.Edges {
+		weights[key{e.From, e.To}] = e.Weight
+	}
+
+	d := SnapshotDiff{New: []Edge{}, Lost: []Edge{}, Changed: []Edge{}}
+	seen := map[key]bool{}
+	for _, e := range after.Edges {
+		k := key{e.From, e.To}
+		seen[k] = true
+		if w, ok := weights[k]; !ok {
+			d.New = append(d.New, e)
+		} else if w != e.Weight {
+			d.Changed = append(d.Changed, Edge{From: e.From, To: e.To, Weight: e.Weight - w})
+		}
+	}
+	for _, e := range before.Edges {
+		if !seen[key{e.From, e.To}] {
+			d.Lost = append(d.Lost, e)
+		}
+	}
+
+	changes := map[string]*CentralityChange{}
+	change := func(userID string) *CentralityChange {
+		if _, ok := changes[userID]; !ok {
+			changes[userID] = &CentralityChange{UserID: userID, Before: Centrality{UserID: userID}, After: Centrality{UserID: userID}}
+		}
+		return changes[userID]
+	}
+	for _, c := range before.Centralities {
+		change(c.UserID).Before = c
+	}
+	for _, c := range after.Centralities {
+		change(c.UserID).After = c
+	}
+	for _, c := range changes {
+		d.Centralities = append(d.Centralities, *c)
+	}
+	sort.Slice(d.Centralities, func(i, j int) bool {
+		a := math.Abs(d.Centralities[i].After.PageRank - d.Centralities[i].Before.PageRank)
+		b := math.Abs(d.Centralities[j].After.PageRank - d.Centralities[j].Before.PageRank)
+		if a != b {
+			return a > b
+		}
+		return d.Centralities[i].UserID < d.Centralities[j].UserID
+	})
+	return d
+}
+
+// Queries replacing a snapshot. Its edges are INTERACTED edges between
+// members tagged with the snapshot ID, so each snapshot is a separate
+// subgraph.
+const (
+	clearSnapshotQuery = `MATCH (s:Snapshot{ID: $id}) DETACH DELETE s
+WITH count(*) AS done
+MATCH (:Member)-[r:INTERACTED{snapshot: $id}]->(:Member) DELETE r`
+
+	saveSnapshotQuery = `MATCH (g:Group{ID: $group})
+CREATE (s:Snapshot{ID: $id, GroupID: $group, period: $period, label: $label,
+	window_start: $start, window_end: $end})-[:OF_GROUP]->(g)
+WITH s
+UNWIND $centralities AS c
+MATCH (m:Member{UserID: c.UserID})
+CREATE (s)-[:INCLUDES{degree: c.degree, pagerank: c.pagerank, betweenness: c.betweenness, eigenvector: c.eigenvector}]->(m)`
+
+	saveSnapshotEdgesQuery = `UNWIND $rows AS e
+MATCH (a:Member{UserID: e.from}), (b:Member{UserID: e.to})
+CREATE (a)-[:INTERACTED{snapshot: $id, weight: e.weight}]->(b)`
+)
+
+// SaveSnapshot replaces a snapshot in the database with a
+// (:Snapshot)-[:INCLUDES]->(:Member) node and INTERACTED edges tagged with
+// its ID.
+func SaveSnapshot(driver *database.Neo4j, s Snapshot) error {
+	session, err := driver.NewWriteSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	centralities := make([]interface{}, len(s.Centralities))
+	for i, c := range s.Centralities {
+		centralities[i] = map[string]interface{}{
+			"UserID":      c.UserID,
+			"degree":      c.Degree,
+			"pagerank":    c.PageRank,
+			"betweenness": c.Betweenness,
+			"eigenvector": c.Eigenvector,
+		}
+	}
+	edges := make([]interface{}, len(s.Edges))
+	for i, e := range s.Edges {
+		edges[i] = map[string]interface{}{"from": e.From, "to": e.To, "weight": e.Weight}
+	}
+
+	_, err = session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		queries := []struct {
+			query  string
+			params map[string]interface{}
+		}{
+			{clearSnapshotQuery, map[string]interface{}{"id": s.ID()}},
+			{saveSnapshotQuery, map[string]interface{}{
+				"id":           s.ID(),
+				"group":        s.GroupID,
+				"period":       s.Period,
+				"label":        s.Label,
+				"start":        s.Window.Start,
+				"end":          s.Window.End,
+				"centralities": centralities,
+			}},
+			{saveSnapshotEdgesQuery, map[string]interface{}{"id": s.ID(), "rows": edges}},
+		}
+		for _, q := range queries {
+			result, err := tx.Run(q.query, q.params)
+			if err != nil {
+				return nil, err
+			}
+			_, err = result.Consume()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+	return err
+}
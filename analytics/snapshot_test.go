@@ -0,0 +1,56 @@
+package analytics
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSnapshotsAndDiff(t *testing.T) {
+	jan, feb := at(2021, 1, 10, 12), at(2021, 2, 10, 12)
+	interactions := []Interaction{
+		{From: "a", To: "b", At: jan},
+		{From: "b", To: "c", At: jan},
+		{From: "a", To: "b", At: feb},
+		{From: "a", To: "b", At: feb},
+		{From: "c", To: "a", At: feb},
+	}
+	snapshots, err := Snapshots("g", interactions, PeriodMonth, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 || snapshots[0].Label != "2021-01" || snapshots[1].ID() != "g:month:1612137600" {
+		t.Fatalf("got %+v", snapshots)
+	}
+
+	d := Diff(snapshots[0], snapshots[1])
+	if !reflect.DeepEqual(d.New, []Edge{{From: "c", To: "a", Weight: 1}}) {
+		t.Errorf("new edges %+v", d.New)
+	}
+	if !reflect.DeepEqual(d.Lost, []Edge{{From: "b", To: "c", Weight: 1}}) {
+		t.Errorf("lost edges %+v", d.Lost)
+	}
+	if !reflect.DeepEqual(d.Changed, []Edge{{From: "a", To: "b", Weight: 1}}) {
+		t.Errorf("changed edges %+v", d.Changed)
+	}
+	if len(d.Centralities) != 3 {
+		t.Errorf("centralities %+v", d.Centralities)
+	}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	snapshots, _ := Snapshots("g", []Interaction{{From: "a", To: "b", At: at(2021, 1, 10, 12)}}, PeriodYear, time.UTC)
+	b := &bytes.Buffer{}
+	err := WriteSnapshot(b, snapshots[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadSnapshot(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, snapshots[0]) {
+		t.Errorf("got %+v, want %+v", read, snapshots[0])
+	}
+}
@@ -0,0 +1,99 @@
+package analytics
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Periods that time is split into.
+const (
+	PeriodDay     = "day"
+	PeriodWeek    = "week"
+	PeriodMonth   = "month"
+	PeriodQuarter = "quarter"
+	PeriodYear    = "year"
+	// PeriodAll is a single window covering all time.
+	PeriodAll = "all"
+)
+
+// Window is a span of time, as unix times. End is exclusive. The window of
+// PeriodAll has zero bounds.
+type Window struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// WindowOf returns the window of a period containing a unix time, in a time
+// zone. Weeks start on Monday.
+func WindowOf(period string, at int, loc *time.Location) (Window, error) {
+	t := time.Unix(int64(at), 0).In(loc)
+	y, m, d := t.Date()
+	var start, end time.Time
+	switch period {
+	case PeriodAll:
+		return Window{}, nil
+	case PeriodDay:
+		start = time.Date(y, m, d, 0, 0, 0, 0, loc)
+		end = start.AddDate(0, 0, 1)
+	case PeriodWeek:
+		start = time.Date(y, m, d-(int(t.Weekday())+6)%7, 0, 0, 0, 0, loc)
+		end = start.AddDate(0, 0, 7)
+	case PeriodMonth:
+		start = time.Date(y, m, 1, 0, 0, 0, 0, loc)
+		end = start.AddDate(0, 1, 0)
+	case PeriodQuarter:
+		start = time.Date(y, m-(m-1)%3, 1, 0, 0, 0, 0, loc)
+		end = start.AddDate(0, 3, 0)
+	case PeriodYear:
+		start = time.Date(y, 1, 1, 0, 0, 0, 0, loc)
+		end = start.AddDate(1, 0, 0)
+	default:
+		return Window{}, fmt.Errorf("unknown period %q", period)
+	}
+	return Window{Start: int(start.Unix()), End: int(end.Unix())}, nil
+}
+
+// Label names the window, e.g. 2021-03 for a month, in a time zone.
+func (w Window) Label(period string, loc *time.Location) string {
+	start := time.Unix(int64(w.Start), 0).In(loc)
+	switch period {
+	case PeriodAll:
+		return "all time"
+	case PeriodMonth:
+		return start.Format("2006-01")
+	case PeriodQuarter:
+		return fmt.Sprintf("%d-Q%d", start.Year(), (int(start.Month())-1)/3+1)
+	case PeriodYear:
+		return start.Format("2006")
+	}
+	return start.Format("2006-01-02")
+}
+
+// WindowedInteractions are the interactions that happened in a window.
+type WindowedInteractions struct {
+	Window       Window
+	Interactions []Interaction
+}
+
+// SplitInteractions groups interactions by the window of a period they
+// happened in, oldest window first.
+func SplitInteractions(interactions []Interaction, period string, loc *time.Location) ([]WindowedInteractions, error) {
+	byWindow := map[Window][]Interaction{}
+	for _, i := range interactions {
+		w, err := WindowOf(period, i.At, loc)
+		if err != nil {
+			return nil, err
+		}
+		byWindow[w] = append(byWindow[w], i)
+	}
+
+	windows := []WindowedInteractions{}
+	for w, i := range byWindow {
+		windows = append(windows, WindowedInteractions{Window: w, Interactions: i})
+	}
+	sort.Slice(windows, func(i, j int) bool {
+		return windows[i].Window.Start < windows[j].Window.Start
+	})
+	return windows, nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+
+	"patrickwthomas.net/groupme-graph/crawl"
+	"patrickwthomas.net/groupme-graph/groupme"
+	"patrickwthomas.net/groupme-graph/local"
+)
+
+func crawlCommand(args []string) {
+	flags := flag.NewFlagSet("crawl", flag.ExitOnError)
+	workers := flags.Int("workers", 4, "number of groups crawled at the same time")
+	qps := flags.Float64("qps", 5, "maximum GroupMe API requests per second across all workers")
+	batchSize := flags.Int("batch", 100, "number of messages written at a time")
+	resume := flags.Bool("resume", false, "continue from the saved checkpoints instead of starting over")
+	flags.Parse(args)
+	if *workers <= 0 {
+		log.Panicf("-workers must be at least 1, got %d", *workers)
+	}
+	if *qps <= 0 {
+		log.Panicf("-qps must be more than 0, got %g", *qps)
+	}
+	if *batchSize <= 0 {
+		log.Panicf("-batch must be at least 1, got %d", *batchSize)
+	}
+
+	settings := loadSettings()
+	matcher, err := settings.Groups.Compile()
+	if err != nil {
+		log.Panic(err)
+	}
+	state, err := local.LoadCrawlState()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	driver := connectNeo4j()
+	g := newGroupMe(settings)
+	g.Client = &http.Client{Transport: crawl.NewLimiter(*qps).Transport(nil)}
+
+	// Stop gracefully on the first interrupt so pending writes are flushed.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		<-interrupts
+		log.Printf("Interrupted, flushing pending writes...")
+		cancel()
+		signal.Stop(interrupts)
+	}()
+
+	groupIndex := []groupme.Group{}
+	it := g.IterateGroups(100, settings.Groups.IncludeFormer)
+	for it.Next(ctx) {
+		groupIndex = append(groupIndex, it.Value())
+	}
+	if it.Err() != nil {
+		log.Panic(it.Err())
+	}
+
+	selected := []groupme.Group{}
+	for _, group := range groupIndex {
+		if matcher.Match(group) {
+			selected = append(selected, group)
+		}
+	}
+	fmt.Printf("Found %d groups, %d selected for sync.\n", len(groupIndex), len(selected))
+
+	crawler := crawl.NewCrawler(g, newStore(settings, driver))
+	crawler.Checkpoints = state
+	crawler.Workers = *workers
+	crawler.BatchSize = *batchSize
+	crawler.Resume = *resume
+
+	err = crawler.Run(ctx, selected)
+	var failed *crawl.FailedError
+	if err == context.Canceled {
+		fmt.Println("Crawl interrupted, progress saved. Continue with: crawl -resume")
+		return
+	} else if errors.As(err, &failed) {
+		// Members are not connected from a partial crawl.
+		fmt.Printf("%d groups failed, the others were saved. Retry them with: crawl -resume\n", len(failed.Groups))
+		log.Panic(err)
+	} else if err != nil {
+		log.Panic(err)
+	}
+
+	groupme.Connect(driver)
+}
@@ -0,0 +1,358 @@
+package crawl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"patrickwthomas.net/groupme-graph/database"
+	"patrickwthomas.net/groupme-graph/groupme"
+	"patrickwthomas.net/groupme-graph/score"
+)
+
+// Status values of a checkpoint.
+const (
+	StatusRunning     = "running"
+	StatusInterrupted = "interrupted"
+	StatusFailed      = "failed"
+	StatusDone        = "done"
+)
+
+// Checkpoint is the progress of the crawl of a single group.
+type Checkpoint struct {
+	GroupID string `json:"group_id"`
+	// BeforeID is the ID of the oldest message that has been committed.
+	BeforeID string `json:"before_id"`
+	// NewestID is the ID of the newest message that has been committed. Every
+	// message from BeforeID up to NewestID is in the store. Catching up pages
+	// oldest first, so it advances with every batch.
+	NewestID string `json:"newest_id"`
+	Messages int    `json:"messages"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+// CheckpointStore persists checkpoints.
+type CheckpointStore interface {
+	Checkpoint(groupID string) (Checkpoint, bool)
+	SaveCheckpoint(cp Checkpoint) error
+}
+
+// Store is where crawled data ends up.
+type Store interface {
+	SaveGroup(group groupme.Group) error
+	SaveMessages(messages []groupme.Message) error
+}
+
+// Neo4jStore writes crawled data into Neo4j.
+type Neo4jStore struct {
+	Driver *database.Neo4j
+	// Scorers rate the text of messages as they are saved. None by default.
+	Scorers []score.Scorer
+}
+
+// SaveGroup saves a group and its members.
+func (s *Neo4jStore) SaveGroup(group groupme.Group) error {
+	group.SaveToNeo4j(s.Driver)
+	return nil
+}
+
+// SaveMessages saves a batch of messages and their scores.
+func (s *Neo4jStore) SaveMessages(messages []groupme.Message) error {
+	err := groupme.SaveMessagesToNeo4j(s.Driver, messages)
+	if err != nil {
+		return err
+	}
+	return score.SaveScores(s.Driver, messages, s.Scorers)
+}
+
+// Crawler fetches the message history of several groups concurrently. Every
+// write goes through a single writer goroutine, so the store does not need to
+// be safe for concurrent use.
+type Crawler struct {
+	G           *groupme.GroupMe
+	Store       Store
+	Checkpoints CheckpointStore
+	// Workers is the number of groups crawled at the same time.
+	Workers int
+	// BatchSize is the number of messages written at a time.
+	BatchSize int
+	// Resume continues from the saved checkpoints instead of starting over.
+	Resume bool
+}
+
+// Phases of a group crawl.
+const (
+	// phaseBackfill walks backwards from the newest message (or the
+	// checkpoint) to the first message of the group.
+	phaseBackfill = iota
+	// phaseCatchUp fetches the messages posted since the newest committed one,
+	// oldest first.
+	phaseCatchUp
+)
+
+// job is a group to crawl, along with where to start.
+type job struct {
+	group      groupme.Group
+	checkpoint Checkpoint
+}
+
+// batch is a unit of work for the writer.
+type batch struct {
+	// group is set on the first batch of a group.
+	group    *groupme.Group
+	groupID  string
+	phase    int
+	messages []groupme.Message
+	// status is set on the last batch of a group.
+	status string
+	err    error
+}
+
+// ErrNoWorkers is returned by Run when the crawler has no workers to crawl
+// with.
+var ErrNoWorkers = errors.New("crawl: at least one worker is needed")
+
+// FailedError is returned by Run when some groups could not be crawled.
+// Their checkpoints are marked failed, so a resumed crawl tries them again.
+type FailedError struct {
+	// Groups are the errors the groups failed with, by group ID.
+	Groups map[string]error
+}
+
+func (e *FailedError) Error() string {
+	ids := []string{}
+	for id := range e.Groups {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	failures := make([]string, len(ids))
+	for i, id := range ids {
+		failures[i] = fmt.Sprintf("%s: %v", id, e.Groups[id])
+	}
+	return fmt.Sprintf("crawl: %d groups failed: %s", len(ids), strings.Join(failures, "; "))
+}
+
+// NewCrawler creates a crawler with default settings.
+func NewCrawler(g *groupme.GroupMe, store Store) *Crawler {
+	c := new(Crawler)
+	c.G = g
+	c.Store = store
+	c.Workers = 4
+	c.BatchSize = 100
+	return c
+}
+
+// Run crawls the groups until they are all done or the context is cancelled.
+// Messages that were already fetched are written and checkpointed before Run
+// returns, even when it was cancelled. When groups failed, the others are
+// still crawled and Run returns a *FailedError.
+func (c *Crawler) Run(ctx context.Context, groups []groupme.Group) error {
+	if c.Workers < 1 {
+		return ErrNoWorkers
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The writer owns the checkpoints once it starts, so the jobs get copies.
+	checkpoints := map[string]Checkpoint{}
+	pending := []job{}
+	for _, group := range groups {
+		cp := Checkpoint{GroupID: group.ID}
+		if c.Resume && c.Checkpoints != nil {
+			if saved, ok := c.Checkpoints.Checkpoint(group.ID); ok {
+				cp = saved
+			}
+		}
+		checkpoints[group.ID] = cp
+		pending = append(pending, job{group: group, checkpoint: cp})
+	}
+
+	jobs := make(chan job)
+	batches := make(chan batch, c.Workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < c.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				c.crawlGroup(ctx, j, batches)
+			}
+		}()
+	}
+
+	// The writer owns failed until it is done.
+	failed := map[string]error{}
+	writerDone := make(chan error, 1)
+	go func() {
+		writerDone <- c.write(batches, checkpoints, failed, cancel)
+	}()
+
+feed:
+	for _, j := range pending {
+		select {
+		case jobs <- j:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	close(batches)
+
+	err := <-writerDone
+	if err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if len(failed) > 0 {
+		return &FailedError{Groups: failed}
+	}
+	return nil
+}
+
+// crawlGroup first finishes the history of a group, then catches up on the
+// messages posted since the newest committed one.
+func (c *Crawler) crawlGroup(ctx context.Context, j job, out chan<- batch) {
+	gc := groupCrawl{crawler: c, out: out, group: &j.group, groupID: j.group.ID}
+
+	newest := j.checkpoint.NewestID
+	var err error
+	// A group that was empty when it was done has nothing to catch up from,
+	// so its history is fetched again.
+	if j.checkpoint.Status != StatusDone || newest == "" {
+		bounds := groupme.MessageBounds{BeforeID: j.checkpoint.BeforeID, PerPage: c.BatchSize}
+		var first string
+		first, err = gc.run(ctx, phaseBackfill, c.G.IterateMessages(gc.groupID, bounds))
+		if newest == "" {
+			newest = first
+		}
+	}
+	if err == nil && newest != "" {
+		it := c.G.IterateMessagesAfter(gc.groupID, newest, c.BatchSize)
+		_, err = gc.run(ctx, phaseCatchUp, it)
+	}
+
+	b := batch{group: gc.group, groupID: gc.groupID, phase: gc.phase, status: StatusDone}
+	if err != nil {
+		if ctx.Err() != nil {
+			b.status = StatusInterrupted
+		} else {
+			b.status = StatusFailed
+			b.err = err
+		}
+	}
+	out <- b
+}
+
+// groupCrawl sends the batches of a single group to the writer.
+type groupCrawl struct {
+	crawler *Crawler
+	out     chan<- batch
+	// group is sent with the first batch and then cleared.
+	group   *groupme.Group
+	groupID string
+	phase   int
+}
+
+// run sends every message of an iterator to the writer. It returns the ID of
+// the first message it found.
+func (gc *groupCrawl) run(ctx context.Context, phase int, it *groupme.MessageIterator) (string, error) {
+	gc.phase = phase
+
+	first := ""
+	messages := []groupme.Message{}
+	for it.Next(ctx) {
+		if first == "" {
+			first = it.Value().ID
+		}
+		messages = append(messages, it.Value())
+		if len(messages) >= gc.crawler.BatchSize {
+			gc.send(messages)
+			messages = []groupme.Message{}
+		}
+	}
+	if len(messages) > 0 {
+		gc.send(messages)
+	}
+	return first, it.Err()
+}
+
+func (gc *groupCrawl) send(messages []groupme.Message) {
+	gc.out <- batch{group: gc.group, groupID: gc.groupID, phase: gc.phase, messages: messages}
+	gc.group = nil
+}
+
+// write is the single writer, which also records the groups that failed.
+// After the first store error it stops the crawl but keeps draining batches
+// so the workers can exit.
+func (c *Crawler) write(batches <-chan batch, checkpoints map[string]Checkpoint, failed map[string]error, cancel context.CancelFunc) error {
+	var writeErr error
+	for b := range batches {
+		if writeErr != nil {
+			continue
+		}
+		writeErr = c.commit(b, checkpoints)
+		if writeErr != nil {
+			cancel()
+		} else if b.status == StatusFailed {
+			failed[b.groupID] = b.err
+		}
+	}
+	return writeErr
+}
+
+// commit writes a batch and then records the progress it made, so that a
+// checkpoint never covers messages that are not in the store.
+func (c *Crawler) commit(b batch, checkpoints map[string]Checkpoint) error {
+	if b.group != nil {
+		err := c.Store.SaveGroup(*b.group)
+		if err != nil {
+			return err
+		}
+	}
+	if len(b.messages) > 0 {
+		err := c.Store.SaveMessages(b.messages)
+		if err != nil {
+			return err
+		}
+	}
+
+	cp := checkpoints[b.groupID]
+	if len(b.messages) > 0 {
+		switch b.phase {
+		case phaseBackfill:
+			if cp.NewestID == "" {
+				cp.NewestID = b.messages[0].ID
+			}
+			cp.BeforeID = b.messages[len(b.messages)-1].ID
+		case phaseCatchUp:
+			newest := b.messages[len(b.messages)-1].ID
+			if groupme.CompareIDs(newest, cp.NewestID) > 0 {
+				cp.NewestID = newest
+			}
+		}
+		cp.Messages += len(b.messages)
+	}
+
+	cp.Status = StatusRunning
+	cp.Error = ""
+	if b.status != "" {
+		cp.Status = b.status
+	}
+	if b.err != nil {
+		cp.Error = b.err.Error()
+	}
+	checkpoints[b.groupID] = cp
+
+	if c.Checkpoints != nil {
+		return c.Checkpoints.SaveCheckpoint(cp)
+	}
+	return nil
+}
@@ -0,0 +1,418 @@
+package crawl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"patrickwthomas.net/groupme-graph/groupme"
+)
+
+// fakeGroupMe serves groups whose messages have IDs 1 up to a count that can
+// grow during a test. Paging past the first message answers 304, and after_id
+// pages oldest first. Groups with a negative count answer 500. Every message
+// it serves is counted in fetched.
+type fakeGroupMe struct {
+	mu      sync.Mutex
+	counts  map[string]int
+	fetched map[string]int
+}
+
+func (f *fakeGroupMe) setCount(groupID string, count int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.counts[groupID] = count
+}
+
+func (f *fakeGroupMe) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// The path is /groups/<id>/messages.
+	groupID := strings.Split(r.URL.Path, "/")[2]
+	f.mu.Lock()
+	top := f.counts[groupID]
+	f.mu.Unlock()
+
+	if top < 0 {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	messages := []groupme.Message{}
+	if after := r.URL.Query().Get("after_id"); after != "" {
+		bottom, _ := strconv.Atoi(after)
+		for id := bottom + 1; id <= top && len(messages) < limit; id++ {
+			messages = append(messages, groupme.Message{ID: fmt.Sprint(id), GroupID: groupID, CreatedAt: id})
+		}
+	} else {
+		if before := r.URL.Query().Get("before_id"); before != "" {
+			top, _ = strconv.Atoi(before)
+			top--
+		}
+		if top < 1 {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		for id := top; id >= 1 && len(messages) < limit; id-- {
+			messages = append(messages, groupme.Message{ID: fmt.Sprint(id), GroupID: groupID, CreatedAt: id})
+		}
+	}
+
+	f.mu.Lock()
+	for _, m := range messages {
+		f.fetched[m.ID]++
+	}
+	f.mu.Unlock()
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"meta":     map[string]interface{}{"code": 200},
+		"response": map[string]interface{}{"count": len(messages), "messages": messages},
+	})
+}
+
+var errCrash = errors.New("simulated crash")
+
+// memoryStore records what was written. It fails every write after failAfter
+// batches, like a process that died, and calls onBatch after every batch.
+type memoryStore struct {
+	messages  map[string]int
+	total     int
+	batches   int
+	failAfter int
+	onBatch   func(batches int)
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{messages: map[string]int{}, failAfter: -1}
+}
+
+func (s *memoryStore) SaveGroup(group groupme.Group) error {
+	return nil
+}
+
+func (s *memoryStore) SaveMessages(messages []groupme.Message) error {
+	if s.failAfter >= 0 && s.batches >= s.failAfter {
+		return errCrash
+	}
+	for _, m := range messages {
+		s.messages[m.ID]++
+	}
+	s.total += len(messages)
+	s.batches++
+	if s.onBatch != nil {
+		s.onBatch(s.batches)
+	}
+	return nil
+}
+
+type memoryCheckpoints struct {
+	mu     sync.Mutex
+	groups map[string]Checkpoint
+}
+
+func (m *memoryCheckpoints) Checkpoint(groupID string) (Checkpoint, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cp, ok := m.groups[groupID]
+	return cp, ok
+}
+
+func (m *memoryCheckpoints) SaveCheckpoint(cp Checkpoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.groups[cp.GroupID] = cp
+	return nil
+}
+
+func setup(counts map[string]int) (*fakeGroupMe, *httptest.Server, *groupme.GroupMe) {
+	fake := &fakeGroupMe{counts: counts, fetched: map[string]int{}}
+	server := httptest.NewServer(fake)
+	g := groupme.NewGroupMe("token")
+	g.API = server.URL
+	return fake, server, g
+}
+
+func newTestCrawler(g *groupme.GroupMe, store Store, checkpoints CheckpointStore) *Crawler {
+	c := NewCrawler(g, store)
+	c.Checkpoints = checkpoints
+	c.BatchSize = 10
+	c.Workers = 2
+	return c
+}
+
+// checkExactlyOnce fails unless every message from 1 to count was written
+// exactly once across the stores.
+func checkExactlyOnce(t *testing.T, count int, stores ...*memoryStore) {
+	seen := map[string]int{}
+	for _, s := range stores {
+		for id, n := range s.messages {
+			seen[id] += n
+		}
+	}
+	for id := 1; id <= count; id++ {
+		if seen[fmt.Sprint(id)] != 1 {
+			t.Errorf("message %d written %d times", id, seen[fmt.Sprint(id)])
+		}
+	}
+	if len(seen) != count {
+		t.Errorf("wrote %d distinct messages, want %d", len(seen), count)
+	}
+}
+
+func TestCrawlerFull(t *testing.T) {
+	_, server, g := setup(map[string]int{"a": 95, "b": 42})
+	defer server.Close()
+
+	store := newMemoryStore()
+	checkpoints := &memoryCheckpoints{groups: map[string]Checkpoint{}}
+	groups := []groupme.Group{{ID: "a"}, {ID: "b"}}
+	err := newTestCrawler(g, store, checkpoints).Run(context.Background(), groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if store.total != 95+42 {
+		t.Errorf("wrote %d messages", store.total)
+	}
+	cp := checkpoints.groups["a"]
+	if cp.Status != StatusDone || cp.NewestID != "95" || cp.BeforeID != "1" || cp.Messages != 95 {
+		t.Errorf("bad checkpoint %+v", cp)
+	}
+}
+
+func TestCrawlerResumeAfterCrash(t *testing.T) {
+	_, server, g := setup(map[string]int{"a": 95})
+	defer server.Close()
+	groups := []groupme.Group{{ID: "a"}}
+	checkpoints := &memoryCheckpoints{groups: map[string]Checkpoint{}}
+
+	crashed := newMemoryStore()
+	crashed.failAfter = 4
+	err := newTestCrawler(g, crashed, checkpoints).Run(context.Background(), groups)
+	if err != errCrash {
+		t.Fatalf("got %v, want the simulated crash", err)
+	}
+	cp := checkpoints.groups["a"]
+	if cp.Status != StatusRunning || cp.BeforeID != "56" || cp.NewestID != "95" {
+		t.Fatalf("bad checkpoint after crash %+v", cp)
+	}
+
+	resumed := newMemoryStore()
+	c := newTestCrawler(g, resumed, checkpoints)
+	c.Resume = true
+	err = c.Run(context.Background(), groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkExactlyOnce(t, 95, crashed, resumed)
+	cp = checkpoints.groups["a"]
+	if cp.Status != StatusDone || cp.BeforeID != "1" || cp.Messages != 95 {
+		t.Errorf("bad checkpoint after resume %+v", cp)
+	}
+}
+
+func TestCrawlerResumeAfterInterrupt(t *testing.T) {
+	_, server, g := setup(map[string]int{"a": 95})
+	defer server.Close()
+	groups := []groupme.Group{{ID: "a"}}
+	checkpoints := &memoryCheckpoints{groups: map[string]Checkpoint{}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupted := newMemoryStore()
+	interrupted.onBatch = func(batches int) {
+		if batches == 3 {
+			cancel()
+		}
+	}
+	err := newTestCrawler(g, interrupted, checkpoints).Run(ctx, groups)
+	if err != context.Canceled {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	if checkpoints.groups["a"].Status != StatusInterrupted {
+		t.Fatalf("bad checkpoint after interrupt %+v", checkpoints.groups["a"])
+	}
+
+	resumed := newMemoryStore()
+	c := newTestCrawler(g, resumed, checkpoints)
+	c.Resume = true
+	err = c.Run(context.Background(), groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkExactlyOnce(t, 95, interrupted, resumed)
+}
+
+func TestCrawlerResumeCatchUp(t *testing.T) {
+	fake, server, g := setup(map[string]int{"a": 50})
+	defer server.Close()
+	groups := []groupme.Group{{ID: "a"}}
+	checkpoints := &memoryCheckpoints{groups: map[string]Checkpoint{}}
+
+	first := newMemoryStore()
+	err := newTestCrawler(g, first, checkpoints).Run(context.Background(), groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// New messages arrive, and the catching up crashes halfway through.
+	fake.setCount("a", 75)
+	crashed := newMemoryStore()
+	crashed.failAfter = 1
+	c := newTestCrawler(g, crashed, checkpoints)
+	c.Resume = true
+	err = c.Run(context.Background(), groups)
+	if err != errCrash {
+		t.Fatalf("got %v, want the simulated crash", err)
+	}
+	cp := checkpoints.groups["a"]
+	if cp.NewestID != "60" {
+		t.Fatalf("bad checkpoint after crash %+v", cp)
+	}
+
+	resumed := newMemoryStore()
+	c = newTestCrawler(g, resumed, checkpoints)
+	c.Resume = true
+	err = c.Run(context.Background(), groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cp = checkpoints.groups["a"]
+	if cp.Status != StatusDone || cp.NewestID != "75" || cp.Messages != 75 {
+		t.Errorf("bad checkpoint after resume %+v", cp)
+	}
+	checkExactlyOnce(t, 75, first, crashed, resumed)
+	// The catching up continues after the last committed batch, so nothing
+	// that was committed is fetched twice. Only what was fetched but never
+	// written before the crash is fetched again.
+	for id := 1; id <= 60; id++ {
+		if n := fake.fetched[fmt.Sprint(id)]; n != 1 {
+			t.Errorf("message %d fetched %d times, want 1", id, n)
+		}
+	}
+}
+
+func TestCrawlerResumeDoneEmpty(t *testing.T) {
+	fake, server, g := setup(map[string]int{"a": 0})
+	defer server.Close()
+	groups := []groupme.Group{{ID: "a"}}
+	checkpoints := &memoryCheckpoints{groups: map[string]Checkpoint{}}
+
+	err := newTestCrawler(g, newMemoryStore(), checkpoints).Run(context.Background(), groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp := checkpoints.groups["a"]; cp.Status != StatusDone || cp.NewestID != "" {
+		t.Fatalf("bad checkpoint of an empty group %+v", cp)
+	}
+
+	// The first messages of a group that was empty are not skipped.
+	fake.setCount("a", 15)
+	resumed := newMemoryStore()
+	c := newTestCrawler(g, resumed, checkpoints)
+	c.Resume = true
+	err = c.Run(context.Background(), groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkExactlyOnce(t, 15, resumed)
+	if cp := checkpoints.groups["a"]; cp.Status != StatusDone || cp.NewestID != "15" {
+		t.Errorf("bad checkpoint after resume %+v", cp)
+	}
+}
+
+func TestCrawlerFailedGroup(t *testing.T) {
+	_, server, g := setup(map[string]int{"a": 25, "broken": -1})
+	defer server.Close()
+
+	store := newMemoryStore()
+	checkpoints := &memoryCheckpoints{groups: map[string]Checkpoint{}}
+	groups := []groupme.Group{{ID: "a"}, {ID: "broken"}}
+	err := newTestCrawler(g, store, checkpoints).Run(context.Background(), groups)
+	var failed *FailedError
+	if !errors.As(err, &failed) || len(failed.Groups) != 1 || failed.Groups["broken"] == nil {
+		t.Fatalf("got %v, want the broken group to fail", err)
+	}
+	checkExactlyOnce(t, 25, store)
+	if cp := checkpoints.groups["broken"]; cp.Status != StatusFailed || cp.Error == "" {
+		t.Errorf("bad checkpoint of the broken group %+v", cp)
+	}
+}
+
+func TestCrawlerNoWorkers(t *testing.T) {
+	_, server, g := setup(map[string]int{"a": 5})
+	defer server.Close()
+
+	c := newTestCrawler(g, newMemoryStore(), nil)
+	c.Workers = 0
+	err := c.Run(context.Background(), []groupme.Group{{ID: "a"}})
+	if err != ErrNoWorkers {
+		t.Errorf("got %v, want ErrNoWorkers", err)
+	}
+}
+
+func TestLimiterUnlimited(t *testing.T) {
+	l := NewLimiter(0)
+	for i := 0; i < 3; i++ {
+		err := l.Wait(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if l.interval != 0 {
+		t.Errorf("got interval %v", l.interval)
+	}
+}
+
+func TestLimiterSharedAcrossWorkers(t *testing.T) {
+	var mu sync.Mutex
+	times := []time.Time{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	const qps = 20
+	client := &http.Client{Transport: NewLimiter(qps).Transport(nil)}
+	var wg sync.WaitGroup
+	for worker := 0; worker < 3; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 3; i++ {
+				response, err := client.Get(server.URL)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				response.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(times) != 9 {
+		t.Fatalf("got %d requests", len(times))
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	// Requests leave the limiter a full interval apart, but arrive at the
+	// server with some jitter.
+	interval := time.Second / qps
+	for i := 1; i < len(times); i++ {
+		if gap := times[i].Sub(times[i-1]); gap < interval/2 {
+			t.Errorf("requests %d and %d only %v apart", i-1, i, gap)
+		}
+	}
+	if total := times[len(times)-1].Sub(times[0]); total < 8*interval*9/10 {
+		t.Errorf("9 requests took %v, want at least %v", total, 8*interval)
+	}
+}
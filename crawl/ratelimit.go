@@ -0,0 +1,72 @@
+package crawl
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Limiter spaces out events so that no more than a fixed number happen per
+// second. It is safe for concurrent use.
+type Limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// NewLimiter creates a limiter allowing qps events per second. A qps of zero
+// or less does not limit anything.
+func NewLimiter(qps float64) *Limiter {
+	l := new(Limiter)
+	if qps > 0 {
+		l.interval = time.Duration(float64(time.Second) / qps)
+	}
+	return l
+}
+
+// Wait blocks until the next event is allowed or the context is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Transport returns an http.RoundTripper that waits on the limiter before
+// every request, so every client sharing it shares the same budget.
+func (l *Limiter) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &limitedTransport{limiter: l, base: base}
+}
+
+type limitedTransport struct {
+	limiter *Limiter
+	base    http.RoundTripper
+}
+
+func (t *limitedTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	err := t.limiter.Wait(request.Context())
+	if err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(request)
+}
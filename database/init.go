@@ -26,8 +26,50 @@ func Init() {
 		log.Panic(err)
 	}
 
+	_, err = session.Run("CREATE CONSTRAINT attachmentIDUnique IF NOT EXISTS ON (n:Attachment) ASSERT n.ID IS UNIQUE", map[string]interface{}{})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	_, err = session.Run("CREATE INDEX nicknameKey IF NOT EXISTS FOR (n:Nickname) ON (n.UserID, n.GroupID, n.value)", map[string]interface{}{})
+	if err != nil {
+		log.Panic(err)
+	}
+
 	_, err = session.Run("CREATE CONSTRAINT userIDUnique IF NOT EXISTS ON (n:Member) ASSERT n.UserID IS UNIQUE", map[string]interface{}{})
 	if err != nil {
 		log.Panic(err)
 	}
+
+	_, err = session.Run("CREATE CONSTRAINT termValueUnique IF NOT EXISTS ON (n:Term) ASSERT n.value IS UNIQUE", map[string]interface{}{})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	_, err = session.Run("CREATE CONSTRAINT linkURLUnique IF NOT EXISTS ON (n:Link) ASSERT n.url IS UNIQUE", map[string]interface{}{})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	_, err = session.Run("CREATE INDEX messageGroup IF NOT EXISTS FOR (n:Message) ON (n.GroupID)", map[string]interface{}{})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	_, err = session.Run("CREATE INDEX sessionGroup IF NOT EXISTS FOR (n:Session) ON (n.GroupID)", map[string]interface{}{})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	// Full-text indexes for the search command. Neo4j keeps them up to date as
+	// nodes are written.
+	_, err = session.Run("CREATE FULLTEXT INDEX messageText IF NOT EXISTS FOR (n:Message) ON EACH [n.Text]", map[string]interface{}{})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	_, err = session.Run("CREATE FULLTEXT INDEX groupText IF NOT EXISTS FOR (n:Group) ON EACH [n.Name, n.Description]", map[string]interface{}{})
+	if err != nil {
+		log.Panic(err)
+	}
 }
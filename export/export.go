@@ -0,0 +1,200 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"patrickwthomas.net/groupme-graph/crawl"
+	"patrickwthomas.net/groupme-graph/groupme"
+)
+
+// GroupTypeDirect is the group type given to direct message conversations,
+// which are imported as groups so they fit into the same graph.
+const GroupTypeDirect = "direct"
+
+// importBatchSize is the number of messages written at a time.
+const importBatchSize = 500
+
+// conversation is the format of conversation.json. Groups look like the
+// groups from the API; direct messages have the other user instead.
+type conversation struct {
+	groupme.Group
+	OtherUser struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"other_user"`
+}
+
+// Conversation is a group or direct message conversation inside an export.
+type Conversation struct {
+	// ID is the name of the folder of the conversation.
+	ID     string
+	Direct bool
+	info   *zip.File
+	msgs   *zip.File
+}
+
+// Archive is an opened GroupMe data export.
+type Archive struct {
+	zip           *zip.ReadCloser
+	Conversations []Conversation
+}
+
+// Open opens a GroupMe data export zip and finds the conversations in it.
+func Open(name string) (*Archive, error) {
+	r, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, err
+	}
+	a := &Archive{zip: r, Conversations: conversations(&r.Reader)}
+	return a, nil
+}
+
+// Close closes the archive.
+func (a *Archive) Close() error {
+	return a.zip.Close()
+}
+
+// conversations pairs up the conversation.json and message.json of every
+// folder in the archive.
+func conversations(r *zip.Reader) []Conversation {
+	byDir := map[string]*Conversation{}
+	for _, f := range r.File {
+		dir, file := path.Split(f.Name)
+		if file != "conversation.json" && file != "message.json" {
+			continue
+		}
+		c, ok := byDir[dir]
+		if !ok {
+			id := path.Base(dir)
+			// Direct message folders are named after both users.
+			c = &Conversation{ID: id, Direct: strings.Contains(id, "+")}
+			byDir[dir] = c
+		}
+		if file == "conversation.json" {
+			c.info = f
+		} else {
+			c.msgs = f
+		}
+	}
+
+	found := []Conversation{}
+	for _, c := range byDir {
+		if c.info != nil && c.msgs != nil {
+			found = append(found, *c)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].ID < found[j].ID })
+	return found
+}
+
+// Group reads the group of the conversation. Direct message conversations
+// become a group of type GroupTypeDirect named after the other user.
+func (c *Conversation) Group() (groupme.Group, error) {
+	f, err := c.info.Open()
+	if err != nil {
+		return groupme.Group{}, err
+	}
+	defer f.Close()
+
+	info := conversation{}
+	err = json.NewDecoder(f).Decode(&info)
+	if err != nil {
+		return groupme.Group{}, fmt.Errorf("reading %s: %v", c.info.Name, err)
+	}
+
+	group := info.Group
+	if c.Direct {
+		group.ID = c.ID
+		group.Type = GroupTypeDirect
+		if group.Name == "" {
+			group.Name = info.OtherUser.Name
+		}
+	}
+	if group.ID == "" {
+		group.ID = c.ID
+	}
+	return group, nil
+}
+
+// EachMessage streams the messages of the conversation in batches, so that
+// large groups never have to fit in memory at once.
+func (c *Conversation) EachMessage(batchSize int, fn func(messages []groupme.Message) error) error {
+	f, err := c.msgs.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	decoder := json.NewDecoder(f)
+	token, err := decoder.Token()
+	if err != nil {
+		return fmt.Errorf("reading %s: %v", c.msgs.Name, err)
+	} else if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("reading %s: expected an array of messages", c.msgs.Name)
+	}
+
+	batch := []groupme.Message{}
+	for decoder.More() {
+		m := groupme.DirectMessage{}
+		err = decoder.Decode(&m)
+		if err != nil {
+			return fmt.Errorf("reading %s: %v", c.msgs.Name, err)
+		}
+		// Direct messages have no group, so they belong to the conversation.
+		if c.Direct || m.GroupID == "" {
+			m.GroupID = c.ID
+		}
+
+		batch = append(batch, m.Message)
+		if len(batch) >= batchSize {
+			err = fn(batch)
+			if err != nil {
+				return err
+			}
+			batch = []groupme.Message{}
+		}
+	}
+	if len(batch) > 0 {
+		return fn(batch)
+	}
+	return nil
+}
+
+// Import writes every conversation of the archive into the store, returning
+// the number of messages written. progress, when not nil, is told about every
+// conversation once it is imported.
+func (a *Archive) Import(store crawl.Store, progress io.Writer) (int, error) {
+	total := 0
+	for i := range a.Conversations {
+		c := &a.Conversations[i]
+		group, err := c.Group()
+		if err != nil {
+			return total, err
+		}
+		err = store.SaveGroup(group)
+		if err != nil {
+			return total, err
+		}
+
+		count := 0
+		err = c.EachMessage(importBatchSize, func(messages []groupme.Message) error {
+			count += len(messages)
+			return store.SaveMessages(messages)
+		})
+		total += count
+		if err != nil {
+			return total, err
+		}
+
+		if progress != nil {
+			fmt.Fprintf(progress, "Imported %d messages from %s (%s).\n", count, group.Name, c.ID)
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,125 @@
+package export
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"patrickwthomas.net/groupme-graph/groupme"
+)
+
+// writeArchive writes a zip with the given files into a temporary directory.
+func writeArchive(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "groupme-export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(dir, "export.zip")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	for fileName, contents := range files {
+		fw, err := w.Create(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(contents))
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+type memoryStore struct {
+	groups   []groupme.Group
+	messages []groupme.Message
+}
+
+func (s *memoryStore) SaveGroup(group groupme.Group) error {
+	s.groups = append(s.groups, group)
+	return nil
+}
+
+func (s *memoryStore) SaveMessages(messages []groupme.Message) error {
+	s.messages = append(s.messages, messages...)
+	return nil
+}
+
+func TestImport(t *testing.T) {
+	name := writeArchive(t, map[string]string{
+		"export/62858190/conversation.json": `{"id":"62858190","name":"Towel","type":"private",
+			"members":[{"user_id":"1","nickname":"Pat"},{"user_id":"2","nickname":"Sam"}]}`,
+		"export/62858190/message.json": `[
+			{"id":"11","group_id":"62858190","user_id":"1","name":"Pat","text":"hi","created_at":100},
+			{"id":"12","group_id":"62858190","user_id":"2","name":"Sam","text":"hello","created_at":160}]`,
+		"export/1+2/conversation.json": `{"id":"1+2","other_user":{"id":"2","name":"Sam"}}`,
+		"export/1+2/message.json":      `[{"id":"21","user_id":"2","recipient_id":"1","text":"psst","created_at":200}]`,
+		"export/62858190/likes.json":   `[]`,
+		"export/orphan/message.json":   `[]`,
+	})
+	defer os.RemoveAll(filepath.Dir(name))
+
+	archive, err := Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+	if len(archive.Conversations) != 2 {
+		t.Fatalf("found %d conversations, want 2", len(archive.Conversations))
+	}
+
+	store := &memoryStore{}
+	total, err := archive.Import(store, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 || len(store.messages) != 3 {
+		t.Errorf("imported %d messages, want 3", total)
+	}
+
+	// Conversations are sorted by folder name, so the direct messages come first.
+	direct, towel := store.groups[0], store.groups[1]
+	if direct.ID != "1+2" || direct.Type != GroupTypeDirect || direct.Name != "Sam" {
+		t.Errorf("bad direct message group %+v", direct)
+	}
+	if towel.Name != "Towel" || len(towel.Members) != 2 {
+		t.Errorf("bad group %+v", towel)
+	}
+	if store.messages[0].GroupID != "1+2" || store.messages[0].Text != "psst" {
+		t.Errorf("bad direct message %+v", store.messages[0])
+	}
+}
+
+func TestEachMessageBatches(t *testing.T) {
+	name := writeArchive(t, map[string]string{
+		"1/conversation.json": `{"id":"1"}`,
+		"1/message.json":      `[{"id":"1"},{"id":"2"},{"id":"3"},{"id":"4"},{"id":"5"}]`,
+	})
+	defer os.RemoveAll(filepath.Dir(name))
+
+	archive, err := Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	sizes := []int{}
+	err = archive.Conversations[0].EachMessage(2, func(messages []groupme.Message) error {
+		sizes = append(sizes, len(messages))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sizes) != 3 || sizes[0] != 2 || sizes[2] != 1 {
+		t.Errorf("got batches %v", sizes)
+	}
+}
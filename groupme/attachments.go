@@ -0,0 +1,222 @@
+package groupme
+
+import (
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Attachment types.
+const (
+	AttachmentTypeImage       = "image"
+	AttachmentTypeLinkedImage = "linked_image"
+	AttachmentTypeVideo       = "video"
+	AttachmentTypeFile        = "file"
+	AttachmentTypeLocation    = "location"
+	AttachmentTypeSplit       = "split"
+	AttachmentTypeEmoji       = "emoji"
+	AttachmentTypeMentions    = "mentions"
+	AttachmentTypeReply       = "reply"
+)
+
+// Attachment is a single attachment of a message. GroupMe sends every type of
+// attachment in the same shape, so only the fields used by its Type are set.
+type Attachment struct {
+	Type        string   `json:"type"`
+	URL         string   `json:"url,omitempty"`
+	PreviewURL  string   `json:"preview_url,omitempty"`
+	FileID      string   `json:"file_id,omitempty"`
+	Lat         string   `json:"lat,omitempty"`
+	Lng         string   `json:"lng,omitempty"`
+	Name        string   `json:"name,omitempty"`
+	Token       string   `json:"token,omitempty"`
+	Placeholder string   `json:"placeholder,omitempty"`
+	Charmap     [][]int  `json:"charmap,omitempty"`
+	UserIDs     []string `json:"user_ids,omitempty"`
+	Loci        [][]int  `json:"loci,omitempty"`
+	ReplyID     string   `json:"reply_id,omitempty"`
+	BaseReplyID string   `json:"base_reply_id,omitempty"`
+}
+
+// MessageAttachment is anything that can be sent as an attachment of a
+// message: the typed attachments below, or an Attachment itself.
+type MessageAttachment interface {
+	AsAttachment() Attachment
+}
+
+// AsAttachment returns the attachment unchanged.
+func (a Attachment) AsAttachment() Attachment {
+	return a
+}
+
+// AttachmentImage is a attachment containing a single image.
+type AttachmentImage struct {
+	Attachment
+	URL string `json:"url"`
+}
+
+// AsAttachment converts the image into a message attachment.
+func (a AttachmentImage) AsAttachment() Attachment {
+	return Attachment{Type: AttachmentTypeImage, URL: a.URL}
+}
+
+// AttachmentLocation contains a location.
+type AttachmentLocation struct {
+	Attachment
+	Lat  string `json:"lat"`
+	Lng  string `json:"lng"`
+	Name string `json:"name"`
+}
+
+// AsAttachment converts the location into a message attachment.
+func (a AttachmentLocation) AsAttachment() Attachment {
+	return Attachment{Type: AttachmentTypeLocation, Lat: a.Lat, Lng: a.Lng, Name: a.Name}
+}
+
+// AttachmentSplit is unknown.
+type AttachmentSplit struct {
+	Attachment
+	Token string `json:"token"`
+}
+
+// AsAttachment converts the split into a message attachment.
+func (a AttachmentSplit) AsAttachment() Attachment {
+	return Attachment{Type: AttachmentTypeSplit, Token: a.Token}
+}
+
+// AttachmentEmoji attaches a GroupMe emoji.
+type AttachmentEmoji struct {
+	Attachment
+	Placeholder string  `json:"placeholder"`
+	Charmap     [][]int `json:"charmap"`
+}
+
+// AsAttachment converts the emoji into a message attachment.
+func (a AttachmentEmoji) AsAttachment() Attachment {
+	return Attachment{Type: AttachmentTypeEmoji, Placeholder: a.Placeholder, Charmap: a.Charmap}
+}
+
+// AttachmentMentions mentions users in the text of a message. Each locus is
+// the [offset, length] of a mention in UTF-16 code units, in the same order as
+// UserIDs.
+type AttachmentMentions struct {
+	UserIDs []string
+	Loci    [][]int
+}
+
+// AsAttachment converts the mentions into a message attachment.
+func (a AttachmentMentions) AsAttachment() Attachment {
+	return Attachment{Type: AttachmentTypeMentions, UserIDs: a.UserIDs, Loci: a.Loci}
+}
+
+// Mention is a user mentioned in a text, at the byte offsets Start to End.
+type Mention struct {
+	UserID string
+	Start  int
+	End    int
+}
+
+// NewMentions computes the loci of mentions in a text.
+func NewMentions(text string, mentions ...Mention) (AttachmentMentions, error) {
+	a := AttachmentMentions{UserIDs: []string{}, Loci: [][]int{}}
+	for _, m := range mentions {
+		if m.Start < 0 || m.End > len(text) || m.Start >= m.End {
+			return AttachmentMentions{}, fmt.Errorf("mention of %s at %d:%d is outside the text", m.UserID, m.Start, m.End)
+		} else if !utf8.RuneStart(text[m.Start]) || (m.End < len(text) && !utf8.RuneStart(text[m.End])) {
+			return AttachmentMentions{}, fmt.Errorf("mention of %s at %d:%d splits a character", m.UserID, m.Start, m.End)
+		}
+		offset := utf16Length(text[:m.Start])
+		a.UserIDs = append(a.UserIDs, m.UserID)
+		a.Loci = append(a.Loci, []int{offset, utf16Length(text[m.Start:m.End])})
+	}
+	return a, nil
+}
+
+// utf16Length is the length of a string in UTF-16 code units, which is how
+// GroupMe counts offsets.
+func utf16Length(s string) int {
+	n := 0
+	for _, r := range s {
+		n += len(utf16.Encode([]rune{r}))
+	}
+	return n
+}
+
+// AttachmentReply makes a message a reply to another one. BaseReplyID is the
+// message at the start of the reply chain, which is ReplyID itself when
+// replying to a message that is not a reply.
+type AttachmentReply struct {
+	ReplyID     string
+	BaseReplyID string
+}
+
+// AsAttachment converts the reply into a message attachment.
+func (a AttachmentReply) AsAttachment() Attachment {
+	base := a.BaseReplyID
+	if base == "" {
+		base = a.ReplyID
+	}
+	return Attachment{Type: AttachmentTypeReply, ReplyID: a.ReplyID, BaseReplyID: base}
+}
+
+// attachmentProperties are the properties of the Attachment node of the
+// index-th attachment of a message.
+func attachmentProperties(m Message, index int, a Attachment) map[string]interface{} {
+	properties := Properties(a)
+	properties["ID"] = fmt.Sprintf("%s:%d", m.ID, index)
+	properties["MessageID"] = m.ID
+	properties["Index"] = index
+	if len(a.UserIDs) > 0 {
+		properties["UserIDs"] = a.UserIDs
+	}
+	if len(a.Charmap) > 0 {
+		properties["Charmap"] = flattenCharmap(a.Charmap)
+	}
+	return properties
+}
+
+// flattenCharmap turns the [pack, index] pairs of an emoji charmap into a
+// single list, which unlike nested lists can be stored in Neo4j.
+func flattenCharmap(charmap [][]int) []int {
+	flat := []int{}
+	for _, entry := range charmap {
+		if len(entry) == 2 {
+			flat = append(flat, entry[0], entry[1])
+		}
+	}
+	return flat
+}
+
+// unflattenCharmap turns the Charmap stored on an Attachment node back into
+// [pack, index] pairs.
+func unflattenCharmap(flat []int) [][]int {
+	charmap := [][]int{}
+	for i := 0; i+1 < len(flat); i += 2 {
+		charmap = append(charmap, []int{flat[i], flat[i+1]})
+	}
+	return charmap
+}
+
+// EmojiAttachments rebuilds the emoji attachments of a message read from
+// Neo4j as a list of [Placeholder, Charmap] pairs.
+func EmojiAttachments(value interface{}) []Attachment {
+	attachments := []Attachment{}
+	pairs, _ := value.([]interface{})
+	for _, p := range pairs {
+		pair, _ := p.([]interface{})
+		if len(pair) != 2 {
+			continue
+		}
+		a := Attachment{Type: AttachmentTypeEmoji}
+		a.Placeholder, _ = pair[0].(string)
+		values, _ := pair[1].([]interface{})
+		flat := make([]int, len(values))
+		for i, v := range values {
+			n, _ := v.(int64)
+			flat[i] = int(n)
+		}
+		a.Charmap = unflattenCharmap(flat)
+		attachments = append(attachments, a)
+	}
+	return attachments
+}
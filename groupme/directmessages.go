@@ -0,0 +1,121 @@
+package groupme
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// DirectMessage is a message sent between two users outside of a group.
+type DirectMessage struct {
+	Message
+	RecipientID    string `json:"recipient_id"`
+	ConversationID string `json:"conversation_id"`
+}
+
+// directMessagesIndex is the index format returned by GroupMe.
+type directMessagesIndex struct {
+	Count          int             `json:"count"`
+	DirectMessages []DirectMessage `json:"direct_messages"`
+}
+
+// Chat is a direct message conversation with another user.
+type Chat struct {
+	CreatedAt     int           `json:"created_at"`
+	UpdatedAt     int           `json:"updated_at"`
+	MessagesCount int           `json:"messages_count"`
+	LastMessage   DirectMessage `json:"last_message"`
+	OtherUser     struct {
+		ID        string `json:"id"`
+		Name      string `json:"name"`
+		AvatarURL string `json:"avatar_url"`
+	} `json:"other_user"`
+}
+
+// ChatsIndex gets the direct message conversations of the user.
+func (g *GroupMe) ChatsIndex(page, perPage int) []Chat {
+	chats := &[]Chat{}
+	urlValues := map[string]string{
+		"page":     fmt.Sprint(page),
+		"per_page": fmt.Sprint(perPage),
+	}
+	_, err := g.groupMeRequest("GET", "/chats", urlValues, chats)
+	if err != nil {
+		log.Panic(err)
+	}
+	return *chats
+}
+
+// DirectMessagesIndex gets a page of direct messages with another user.
+func (g *GroupMe) DirectMessagesIndex(otherUserID, beforeID, sinceID string) []DirectMessage {
+	messages, err := g.directMessagesIndex(context.Background(), otherUserID, beforeID, sinceID)
+	if err != nil && err != ErrNotModified {
+		log.Panic(err)
+	}
+	return messages
+}
+
+func (g *GroupMe) directMessagesIndex(ctx context.Context, otherUserID, beforeID, sinceID string) ([]DirectMessage, error) {
+	messages := &directMessagesIndex{}
+	urlValues := map[string]string{
+		"other_user_id": otherUserID,
+	}
+
+	if beforeID != "" {
+		urlValues["before_id"] = beforeID
+	} else if sinceID != "" {
+		urlValues["since_id"] = sinceID
+	}
+
+	_, err := g.groupMeRequestContext(ctx, "GET", "/direct_messages", urlValues, messages)
+	return messages.DirectMessages, err
+}
+
+type directMessageCreate struct {
+	DirectMessage struct {
+		SourceGUID  string       `json:"source_guid"`
+		RecipientID string       `json:"recipient_id"`
+		Text        string       `json:"text"`
+		Attachments []Attachment `json:"attachments"`
+	} `json:"direct_message"`
+}
+
+type directMessageCreated struct {
+	DirectMessage DirectMessage `json:"direct_message"`
+}
+
+// DirectMessagesCreate sends a direct message to another user and returns it
+// as GroupMe saved it. Like MessagesCreate, retries are safe.
+func (g *GroupMe) DirectMessagesCreate(otherUserID, text string, attachments ...MessageAttachment) (DirectMessage, error) {
+	return g.DirectMessagesCreateContext(context.Background(), otherUserID, text, attachments...)
+}
+
+// DirectMessagesCreateContext is DirectMessagesCreate, giving up on retries
+// when the context is done.
+func (g *GroupMe) DirectMessagesCreateContext(ctx context.Context, otherUserID, text string, attachments ...MessageAttachment) (DirectMessage, error) {
+	values := directMessageCreate{}
+	values.DirectMessage.SourceGUID = NewSourceGUID()
+	values.DirectMessage.RecipientID = otherUserID
+	values.DirectMessage.Text = text
+	values.DirectMessage.Attachments = convertAttachments(attachments)
+	err := validateMessage(text, values.DirectMessage.Attachments)
+	if err != nil {
+		return DirectMessage{}, err
+	}
+
+	result := &directMessageCreated{}
+	err = retryCreate(ctx, func() (meta, error) {
+		return g.groupMeRequestPostObjectContext(ctx, "/direct_messages", values, result)
+	})
+	return result.DirectMessage, err
+}
+
+// DirectMessagesCreateWithImages uploads pictures from the harddisk and sends
+// them to another user with some text.
+func (g *GroupMe) DirectMessagesCreateWithImages(otherUserID, text string, files ...string) (DirectMessage, error) {
+	attachments, err := g.ImageAttachments(files...)
+	if err != nil {
+		return DirectMessage{}, err
+	}
+	return g.DirectMessagesCreate(otherUserID, text, rawAttachments(attachments)...)
+}
@@ -0,0 +1,124 @@
+package groupme
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// EmojiUse is a GroupMe emoji in the text of a message. Offset is the byte
+// offset of its placeholder in the text.
+type EmojiUse struct {
+	Offset int
+	PackID int
+	Index  int
+}
+
+// EmojiCatalog names the emoji of GroupMe's emoji packs.
+type EmojiCatalog interface {
+	// EmojiName returns the name of an emoji, or false when it is unknown.
+	EmojiName(packID, index int) (string, bool)
+}
+
+// EmojiPack is a GroupMe emoji pack.
+type EmojiPack struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	// Emoji are the names of the emoji of the pack, by index.
+	Emoji []string `json:"emoji"`
+}
+
+// EmojiPacks is an emoji catalog of known packs by ID.
+type EmojiPacks map[int]EmojiPack
+
+// EmojiName returns the name of an emoji from its pack.
+func (p EmojiPacks) EmojiName(packID, index int) (string, bool) {
+	pack, ok := p[packID]
+	if !ok || index < 0 || index >= len(pack.Emoji) || pack.Emoji[index] == "" {
+		return "", false
+	}
+	return pack.Emoji[index], true
+}
+
+// LoadEmojiPacks reads an emoji catalog from a JSON file holding a list of
+// packs, e.g. [{"id": 1, "name": "Classic", "emoji": ["smile", "frown"]}].
+func LoadEmojiPacks(name string) (EmojiPacks, error) {
+	contents, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	list := []EmojiPack{}
+	err = json.Unmarshal(contents, &list)
+	if err != nil {
+		return nil, fmt.Errorf("reading emoji packs from %s: %v", name, err)
+	}
+	packs := EmojiPacks{}
+	for _, pack := range list {
+		packs[pack.ID] = pack
+	}
+	return packs, nil
+}
+
+// emojiAttachment returns the emoji attachment of a message, if it has one.
+func (m Message) emojiAttachment() (Attachment, bool) {
+	for _, a := range m.Attachments {
+		if a.Type == AttachmentTypeEmoji && a.Placeholder != "" {
+			return a, true
+		}
+	}
+	return Attachment{}, false
+}
+
+// DecodeEmoji finds the GroupMe emoji in the text of a message. Every
+// placeholder character in the text stands for the next entry of the charmap
+// of the emoji attachment. Placeholders without an entry are left out.
+func (m Message) DecodeEmoji() []EmojiUse {
+	a, ok := m.emojiAttachment()
+	if !ok {
+		return nil
+	}
+	uses := []EmojiUse{}
+	offset := 0
+	for _, entry := range a.Charmap {
+		i := strings.Index(m.Text[offset:], a.Placeholder)
+		if i == -1 {
+			break
+		}
+		offset += i
+		if len(entry) == 2 {
+			uses = append(uses, EmojiUse{Offset: offset, PackID: entry[0], Index: entry[1]})
+		}
+		offset += len(a.Placeholder)
+	}
+	return uses
+}
+
+// RenderText returns the text of a message with its GroupMe emoji written as
+// :name:. Emoji missing from the catalog, which may be nil, are written as
+// :pack-index:.
+func (m Message) RenderText(catalog EmojiCatalog) string {
+	a, ok := m.emojiAttachment()
+	if !ok {
+		return m.Text
+	}
+
+	b := strings.Builder{}
+	last := 0
+	for _, use := range m.DecodeEmoji() {
+		b.WriteString(m.Text[last:use.Offset])
+		b.WriteString(":" + emojiName(catalog, use.PackID, use.Index) + ":")
+		last = use.Offset + len(a.Placeholder)
+	}
+	b.WriteString(m.Text[last:])
+	return b.String()
+}
+
+func emojiName(catalog EmojiCatalog, packID, index int) string {
+	if catalog != nil {
+		if name, ok := catalog.EmojiName(packID, index); ok {
+			return name
+		}
+	}
+	return fmt.Sprintf("%d-%d", packID, index)
+}
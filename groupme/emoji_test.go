@@ -0,0 +1,84 @@
+package groupme
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func emojiMessage(text string, charmap ...[]int) Message {
+	return Message{Text: text, Attachments: []Attachment{
+		AttachmentEmoji{Placeholder: "�", Charmap: charmap}.AsAttachment(),
+	}}
+}
+
+func TestDecodeEmoji(t *testing.T) {
+	m := emojiMessage("hi � and �", []int{1, 62}, []int{2, 3})
+	uses := m.DecodeEmoji()
+	want := []EmojiUse{{Offset: 3, PackID: 1, Index: 62}, {Offset: 11, PackID: 2, Index: 3}}
+	if !reflect.DeepEqual(uses, want) {
+		t.Errorf("got %+v, want %+v", uses, want)
+	}
+
+	if (Message{Text: "hi �"}).DecodeEmoji() != nil {
+		t.Error("decoded emoji without an emoji attachment")
+	}
+}
+
+func TestRenderText(t *testing.T) {
+	packs := EmojiPacks{1: {ID: 1, Emoji: []string{"smile", "frown"}}}
+	m := emojiMessage("� so ��", []int{1, 0}, []int{1, 1}, []int{4, 2})
+
+	if text := m.RenderText(packs); text != ":smile: so :frown::4-2:" {
+		t.Errorf("got %q", text)
+	}
+	if text := m.RenderText(nil); text != ":1-0: so :1-1::4-2:" {
+		t.Errorf("got %q without a catalog", text)
+	}
+}
+
+func TestRenderTextShortCharmap(t *testing.T) {
+	m := emojiMessage("��", []int{1, 0})
+	if text := m.RenderText(nil); text != ":1-0:�" {
+		t.Errorf("got %q", text)
+	}
+}
+
+func TestLoadEmojiPacks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "emoji")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "packs.json")
+	err = ioutil.WriteFile(name, []byte(`[{"id": 1, "name": "Classic", "emoji": ["smile", "frown"]}]`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packs, err := LoadEmojiPacks(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, ok := packs.EmojiName(1, 1); !ok || name != "frown" {
+		t.Errorf("got %q %v", name, ok)
+	}
+	if _, ok := packs.EmojiName(1, 2); ok {
+		t.Error("found an emoji past the end of the pack")
+	}
+}
+
+func TestEmojiAttachments(t *testing.T) {
+	flat := flattenCharmap([][]int{{1, 0}, {2, 5}})
+	if !reflect.DeepEqual(flat, []int{1, 0, 2, 5}) {
+		t.Errorf("got %v", flat)
+	}
+	stored := []interface{}{[]interface{}{"��", []interface{}{int64(1), int64(0), int64(2), int64(5)}}}
+	want := emojiMessage("", []int{1, 0}, []int{2, 5}).Attachments
+	want[0].Placeholder = "��"
+	if got := EmojiAttachments(stored); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
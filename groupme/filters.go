@@ -0,0 +1,132 @@
+package groupme
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// filterDateLayout is the date format used for the activity bounds of a filter.
+const filterDateLayout = "2006-01-02"
+
+// GroupFilter selects which groups are synced. A group is kept when it matches
+// at least one include rule (or there are no include rules), matches none of
+// the exclude rules and falls within every configured range.
+type GroupFilter struct {
+	IncludeIDs    []string `json:"include_ids"`
+	IncludeNames  []string `json:"include_names"`
+	IncludeTypes  []string `json:"include_types"`
+	ExcludeIDs    []string `json:"exclude_ids"`
+	ExcludeNames  []string `json:"exclude_names"`
+	ExcludeTypes  []string `json:"exclude_types"`
+	MinMembers    int      `json:"min_members"`
+	MaxMembers    int      `json:"max_members"`
+	ActiveAfter   string   `json:"active_after"`
+	ActiveBefore  string   `json:"active_before"`
+	IncludeFormer bool     `json:"include_former"`
+}
+
+// GroupMatcher is a compiled GroupFilter.
+type GroupMatcher struct {
+	filter       GroupFilter
+	includeNames []*regexp.Regexp
+	excludeNames []*regexp.Regexp
+	activeAfter  time.Time
+	activeBefore time.Time
+}
+
+// Compile validates the filter and prepares it for matching.
+func (f GroupFilter) Compile() (*GroupMatcher, error) {
+	m := &GroupMatcher{filter: f}
+
+	for _, pattern := range f.IncludeNames {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("bad include name pattern %q: %v", pattern, err)
+		}
+		m.includeNames = append(m.includeNames, re)
+	}
+	for _, pattern := range f.ExcludeNames {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("bad exclude name pattern %q: %v", pattern, err)
+		}
+		m.excludeNames = append(m.excludeNames, re)
+	}
+
+	var err error
+	if f.ActiveAfter != "" {
+		m.activeAfter, err = time.Parse(filterDateLayout, f.ActiveAfter)
+		if err != nil {
+			return nil, fmt.Errorf("bad active_after date %q: %v", f.ActiveAfter, err)
+		}
+	}
+	if f.ActiveBefore != "" {
+		m.activeBefore, err = time.Parse(filterDateLayout, f.ActiveBefore)
+		if err != nil {
+			return nil, fmt.Errorf("bad active_before date %q: %v", f.ActiveBefore, err)
+		}
+	}
+	if f.MaxMembers > 0 && f.MinMembers > f.MaxMembers {
+		return nil, fmt.Errorf("min_members (%d) is greater than max_members (%d)", f.MinMembers, f.MaxMembers)
+	}
+
+	return m, nil
+}
+
+// Match reports whether the group passes the filter.
+func (m *GroupMatcher) Match(g Group) bool {
+	f := m.filter
+
+	hasInclude := len(f.IncludeIDs) > 0 || len(m.includeNames) > 0 || len(f.IncludeTypes) > 0
+	if hasInclude && !contains(f.IncludeIDs, g.ID) && !anyMatch(m.includeNames, g.Name) && !contains(f.IncludeTypes, g.Type) {
+		return false
+	}
+	if contains(f.ExcludeIDs, g.ID) || anyMatch(m.excludeNames, g.Name) || contains(f.ExcludeTypes, g.Type) {
+		return false
+	}
+
+	if f.MinMembers > 0 && len(g.Members) < f.MinMembers {
+		return false
+	}
+	if f.MaxMembers > 0 && len(g.Members) > f.MaxMembers {
+		return false
+	}
+
+	lastActive := g.LastActivity()
+	if !m.activeAfter.IsZero() && lastActive.Before(m.activeAfter) {
+		return false
+	}
+	if !m.activeBefore.IsZero() && !lastActive.Before(m.activeBefore) {
+		return false
+	}
+
+	return true
+}
+
+// LastActivity is the time of the last message in the group, falling back to
+// the last update of the group itself.
+func (g *Group) LastActivity() time.Time {
+	if g.Messages.LastMessageCreatedAt > 0 {
+		return time.Unix(int64(g.Messages.LastMessageCreatedAt), 0)
+	}
+	return time.Unix(int64(g.UpdatedAt), 0)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func anyMatch(patterns []*regexp.Regexp, s string) bool {
+	for _, re := range patterns {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
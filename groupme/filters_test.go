@@ -0,0 +1,85 @@
+package groupme
+
+import (
+	"testing"
+	"time"
+)
+
+func testGroup(id, name, groupType string, members int, lastActive time.Time) Group {
+	g := Group{ID: id, Name: name, Type: groupType}
+	g.Members = make([]Member, members)
+	g.Messages.LastMessageCreatedAt = int(lastActive.Unix())
+	return g
+}
+
+func TestGroupFilterEmptyMatchesAll(t *testing.T) {
+	m, err := GroupFilter{}.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Match(testGroup("1", "Towel", "private", 3, time.Now())) {
+		t.Fail()
+	}
+}
+
+func TestGroupFilterIncludeExclude(t *testing.T) {
+	m, err := GroupFilter{
+		IncludeIDs:   []string{"1"},
+		IncludeNames: []string{"^Club"},
+		ExcludeNames: []string{"(?i)alumni"},
+	}.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]bool{
+		"1": true,
+		"2": true,
+		"3": false,
+		"4": false,
+	}
+	groups := map[string]Group{
+		"1": testGroup("1", "Towel", "private", 3, time.Now()),
+		"2": testGroup("2", "Club Frisbee", "private", 3, time.Now()),
+		"3": testGroup("3", "Club Alumni", "private", 3, time.Now()),
+		"4": testGroup("4", "Something Else", "private", 3, time.Now()),
+	}
+	for id, want := range cases {
+		if got := m.Match(groups[id]); got != want {
+			t.Errorf("group %s: got %v, want %v", id, got, want)
+		}
+	}
+}
+
+func TestGroupFilterRanges(t *testing.T) {
+	m, err := GroupFilter{
+		MinMembers:  2,
+		MaxMembers:  10,
+		ActiveAfter: "2020-01-01",
+	}.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recent := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	old := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	if !m.Match(testGroup("1", "a", "private", 5, recent)) {
+		t.Error("group in range was rejected")
+	}
+	if m.Match(testGroup("2", "b", "private", 1, recent)) {
+		t.Error("group with too few members was accepted")
+	}
+	if m.Match(testGroup("3", "c", "private", 11, recent)) {
+		t.Error("group with too many members was accepted")
+	}
+	if m.Match(testGroup("4", "d", "private", 5, old)) {
+		t.Error("inactive group was accepted")
+	}
+}
+
+func TestGroupFilterBadPattern(t *testing.T) {
+	_, err := GroupFilter{IncludeNames: []string{"("}}.Compile()
+	if err == nil {
+		t.Fail()
+	}
+}
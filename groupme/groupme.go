@@ -2,8 +2,12 @@ package groupme
 
 import (
 	"bytes"
+	"context"
+	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -19,6 +23,12 @@ import (
 type GroupMe struct {
 	// APIKey is the key used to authenticate through GroupMe.
 	APIKey string
+	// API is the base URL of the GroupMe API.
+	API string
+	// ImageAPI is the base URL of the GroupMe image service.
+	ImageAPI string
+	// Client is the HTTP client used for requests.
+	Client *http.Client
 }
 
 type meta struct {
@@ -37,95 +47,118 @@ var quotePattern = regexp.MustCompile(`[{,]"[\w_]+":`)
 func NewGroupMe(apiKey string) *GroupMe {
 	g := new(GroupMe)
 	g.APIKey = apiKey
+	g.API = GroupMeAPI
+	g.ImageAPI = GroupMeImageAPI
+	g.Client = http.DefaultClient
 	return g
 }
 
+// ErrNotModified is returned by GroupMe when there is nothing more to page
+// through, e.g. when asking for messages before the first one in a group.
+var ErrNotModified = errors.New("groupme: not modified")
+
 func letterOpener(responseBody []byte, dest interface{}) (meta, error) {
-	meta := meta{}
-	unmarshalledResponse := envelope{Meta: meta, Response: dest}
+	unmarshalledResponse := envelope{Response: dest}
 	err := json.Unmarshal(responseBody, &unmarshalledResponse)
 	if err != nil {
-		log.Panic(err)
+		return unmarshalledResponse.Meta, err
 	} else if unmarshalledResponse.Meta.Code/100 != 2 {
-		panic(fmt.Sprintf("Request failed. Code: %d. Message: %v", unmarshalledResponse.Meta.Code, unmarshalledResponse.Meta.Errors))
+		return unmarshalledResponse.Meta, fmt.Errorf("request failed. Code: %d. Message: %v", unmarshalledResponse.Meta.Code, unmarshalledResponse.Meta.Errors)
 	}
-	return meta, err
+	return unmarshalledResponse.Meta, nil
 }
 
 func (g *GroupMe) groupMeRequest(method, requestSubDir string, values map[string]string, dest interface{}) (meta, error) {
-	request, err := http.NewRequest(method, GroupMeAPI+requestSubDir, nil)
-	if err != nil {
-		return meta{}, err
-	}
+	return g.groupMeRequestContext(context.Background(), method, requestSubDir, values, dest)
+}
 
-	// Build the queries and get a response. Could be either GET or POST
-	var response *http.Response
-	query := request.URL.Query()
+func (g *GroupMe) groupMeRequestContext(ctx context.Context, method, requestSubDir string, values map[string]string, dest interface{}) (meta, error) {
+	query := url.Values{}
 	query.Add("token", g.APIKey)
+
+	// Build the queries and get a response. Could be either GET or POST
+	var body io.Reader
 	if method == "GET" {
-		if values != nil {
-			for k, v := range values {
-				query.Add(k, v)
-			}
+		for k, v := range values {
+			query.Add(k, v)
 		}
-		request.URL.RawQuery = query.Encode()
-		response, err = http.Get(request.URL.String())
 	} else if method == "POST" {
 		postData := url.Values{}
-		if values != nil {
-			for k, v := range values {
-				postData.Add(k, v)
-			}
+		for k, v := range values {
+			postData.Add(k, v)
 		}
-		request.URL.RawQuery = query.Encode()
-		response, err = http.PostForm(request.URL.String(), postData)
+		body = strings.NewReader(postData.Encode())
 	}
+	request, err := http.NewRequestWithContext(ctx, method, g.API+requestSubDir+"?"+query.Encode(), body)
 	if err != nil {
 		return meta{}, err
 	}
-	defer response.Body.Close()
-
-	// Get the message body out of the response.
-	body, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		return meta{}, err
+	if method == "POST" {
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	}
 
-	// Extract the response from the GroupMe envelope.
-	return letterOpener(body, dest)
+	return g.do(request, dest)
 }
 
 func (g *GroupMe) groupMeRequestPostObject(requestSubDir string, values interface{}, dest interface{}) (meta, error) {
-	request, err := http.NewRequest("POST", GroupMeAPI+requestSubDir, nil)
-	if err != nil {
-		return meta{}, err
-	}
+	return g.groupMeRequestPostObjectContext(context.Background(), requestSubDir, values, dest)
+}
 
+func (g *GroupMe) groupMeRequestPostObjectContext(ctx context.Context, requestSubDir string, values interface{}, dest interface{}) (meta, error) {
 	// Marshall the input object.
 	marshalled, err := json.Marshal(values)
 	if err != nil {
 		return meta{}, err
 	}
 
-	// Build the queries and get a response. Could be either GET or POST
-	var response *http.Response
-	query := request.URL.Query()
+	query := url.Values{}
 	query.Add("token", g.APIKey)
-	request.URL.RawQuery = query.Encode()
-	response, err = http.Post(request.URL.String(), "application/json", bytes.NewReader(marshalled))
+	request, err := http.NewRequestWithContext(ctx, "POST", g.API+requestSubDir+"?"+query.Encode(), bytes.NewReader(marshalled))
+	if err != nil {
+		return meta{}, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	return g.do(request, dest)
+}
+
+// do sends a request and extracts the response from the GroupMe envelope.
+func (g *GroupMe) do(request *http.Request, dest interface{}) (meta, error) {
+	response, err := g.Client.Do(request)
 	if err != nil {
 		return meta{}, err
 	}
 	defer response.Body.Close()
 
+	// GroupMe answers with an empty 304 when there is nothing left to page through.
+	if response.StatusCode == http.StatusNotModified {
+		return meta{Code: response.StatusCode}, ErrNotModified
+	}
+
 	// Get the message body out of the response.
 	body, err := ioutil.ReadAll(response.Body)
 	if err != nil {
 		return meta{}, err
 	}
 
-	// Extract the response from the GroupMe envelope.
-	return letterOpener(body, dest)
+	// Extract the response from the GroupMe envelope. Errors like rate limits
+	// can come without one, so the HTTP status stands in for its code.
+	m, err := letterOpener(body, dest)
+	if m.Code == 0 {
+		m.Code = response.StatusCode
+	}
+	return m, err
+}
+
+// NewSourceGUID makes a random GUID for a new message. GroupMe drops messages
+// whose GUID it has already seen, which makes retries safe.
+func NewSourceGUID() string {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		log.Panic(err)
+	}
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
 }
 
 func noQuotes(s string) string {
@@ -156,6 +189,62 @@ func Melt(v interface{}) string {
 	return strings.Join(cypherParts, ", ")
 }
 
+// Properties returns the string, int and bool fields of a struct as a map
+// suitable for use as Cypher parameters. It picks the same fields as Melt.
+func Properties(v interface{}) map[string]interface{} {
+	properties := map[string]interface{}{}
+
+	value := reflect.ValueOf(v)
+	typeOfS := value.Type()
+	for i := 0; i < value.NumField(); i++ {
+		switch typeOfS.Field(i).Type.Name() {
+		case "string", "int", "bool":
+			properties[typeOfS.Field(i).Name] = value.Field(i).Interface()
+		}
+	}
+
+	return properties
+}
+
+// connectQueries link nodes that were saved independently of each other.
+var connectQueries = []string{
+	`MATCH (m:Member), (n:Message) WHERE n.UserID = m.UserID
+	MERGE (m)-[:AUTHORED]->(n)`,
+	`MATCH (m:Group), (n:Message) WHERE n.GroupID = m.ID
+	MERGE (m)-[:HAS_MESSAGE]->(n)`,
+	`MATCH (g:Group), (e:MembershipEvent) WHERE e.GroupID = g.ID
+	MERGE (g)-[:HAS_EVENT]->(e)`,
+	`MATCH (m:Member), (e:MembershipEvent) WHERE e.SubjectUserID = m.UserID
+	MERGE (m)-[:SUBJECT_OF]->(e)`,
+	`MATCH (m:Member), (e:MembershipEvent) WHERE e.ActorUserID = m.UserID
+	MERGE (m)-[:ACTOR_OF]->(e)`,
+	`MATCH (m:Member), (n:Nickname) WHERE n.UserID = m.UserID
+	MERGE (m)-[:HAD_NICKNAME]->(n)`,
+	`MATCH (g:Group), (n:Nickname) WHERE n.GroupID = g.ID
+	MERGE (n)-[:IN_GROUP]->(g)`,
+	`MATCH (m:Member), (a:Avatar) WHERE a.UserID = m.UserID
+	MERGE (m)-[:HAD_AVATAR]->(a)`,
+	`MATCH (g:Group), (i:GroupImage) WHERE i.GroupID = g.ID
+	MERGE (g)-[:HAD_IMAGE]->(i)`,
+	// Events parsed from the text only have nicknames, which are matched
+	// against the members of the group.
+	`MATCH (g:Group)-[:HAS_EVENT]->(e:MembershipEvent), (m:Member)-[r:MEMBER_OF]->(g)
+	WHERE e.SubjectUserID = "" AND (r.nickname = e.SubjectName OR m.Nickname = e.SubjectName)
+	MERGE (m)-[:SUBJECT_OF]->(e)`,
+	// Replay the membership events in order onto the MEMBER_OF edges.
+	`MATCH (g:Group)-[:HAS_EVENT]->(e:MembershipEvent)<-[:SUBJECT_OF]-(m:Member)
+	WITH g, m, e ORDER BY e.CreatedAt
+	WITH g, m, collect(e) AS events
+	WITH g, m,
+		[e IN events WHERE e.Type = "ADDED" | e.CreatedAt] AS added,
+		[e IN events WHERE e.Type IN ["LEFT", "REMOVED"] | e.CreatedAt] AS gone,
+		[e IN events WHERE e.Type = "RENAMED" | e.Nickname] AS names
+	MERGE (m)-[r:MEMBER_OF]->(g)
+	SET r.joined_at = CASE WHEN size(added) > 0 THEN added[0] ELSE r.joined_at END,
+		r.left_at = CASE WHEN size(gone) > 0 AND (size(added) = 0 OR gone[-1] > added[-1]) THEN gone[-1] ELSE null END,
+		r.nickname = CASE WHEN size(names) > 0 THEN names[-1] ELSE r.nickname END`,
+}
+
 // Connect connects the data in the graph database as best it can.
 func Connect(driver *database.Neo4j) {
 	session, err := driver.NewWriteSession()
@@ -164,21 +253,14 @@ func Connect(driver *database.Neo4j) {
 	}
 	defer session.Close()
 
-	result, err := session.Run(`MATCH (m:Member), (n:Message) WHERE n.UserID = m.UserID
-	MERGE (m)-[:AUTHORED]->(n)`, map[string]interface{}{})
-	e := result.Err()
-	if err != nil {
-		log.Panic(err)
-	} else if e != nil {
-		panic(e)
-	}
-
-	result, err = session.Run(`MATCH (m:Group), (n:Message) WHERE n.GroupID = m.ID
-	MERGE (m)-[:HAS_MESSAGE]->(n)`, map[string]interface{}{})
-	e = result.Err()
-	if err != nil {
-		log.Panic(err)
-	} else if e != nil {
-		panic(e)
+	for _, query := range connectQueries {
+		result, err := session.Run(query, map[string]interface{}{})
+		if err != nil {
+			log.Panic(err)
+		}
+		_, err = result.Consume()
+		if err != nil {
+			log.Panic(err)
+		}
 	}
 }
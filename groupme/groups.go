@@ -1,9 +1,12 @@
 package groupme
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"time"
 
+	"github.com/neo4j/neo4j-go-driver/neo4j"
 	"patrickwthomas.net/groupme-graph/database"
 )
 
@@ -24,19 +27,10 @@ type Group struct {
 		LastMessageID        string `json:"last_message_id"`
 		LastMessageCreatedAt int    `json:"last_message_created_at"`
 		Preview              struct {
-			Nickname    string `json:"nickname"`
-			Text        string `json:"text"`
-			ImageURL    string `json:"image_url"`
-			Attachments []struct {
-				Type        string  `json:"type"`
-				URL         string  `json:"url,omitempty"`
-				Lat         string  `json:"lat,omitempty"`
-				Lng         string  `json:"lng,omitempty"`
-				Name        string  `json:"name,omitempty"`
-				Token       string  `json:"token,omitempty"`
-				Placeholder string  `json:"placeholder,omitempty"`
-				Charmap     [][]int `json:"charmap,omitempty"`
-			} `json:"attachments"`
+			Nickname    string       `json:"nickname"`
+			Text        string       `json:"text"`
+			ImageURL    string       `json:"image_url"`
+			Attachments []Attachment `json:"attachments"`
 		} `json:"preview"`
 	} `json:"messages"`
 }
@@ -63,6 +57,20 @@ func (g *GroupMe) GroupsIndex(page, perPage int, omitMemberships bool) []Group {
 	return *groups
 }
 
+// GroupsAll pages through the whole groups index, optionally followed by the
+// groups the user has left.
+func (g *GroupMe) GroupsAll(perPage int, includeFormer bool) []Group {
+	groups := []Group{}
+	it := g.IterateGroups(perPage, includeFormer)
+	for it.Next(context.Background()) {
+		groups = append(groups, it.Value())
+	}
+	if it.Err() != nil {
+		log.Panic(it.Err())
+	}
+	return groups
+}
+
 // GroupsFormer gets the groups index from GroupMe.
 func (g *GroupMe) GroupsFormer() []Group {
 	groups := &[]Group{}
@@ -166,19 +174,60 @@ func (g *Group) SaveToNeo4j(driver *database.Neo4j) {
 	}
 	defer session.Close()
 
-	result, err := session.Run(fmt.Sprintf("MERGE (n:Group{%s})", Melt(*g)), map[string]interface{}{})
-	e := result.Err()
+	result, err := session.Run("MERGE (n:Group{ID: $group.ID}) SET n += $group",
+		map[string]interface{}{"group": Properties(*g)})
+	if err != nil {
+		log.Panic(err)
+	}
+	_, err = result.Consume()
 	if err != nil {
 		log.Panic(err)
-	} else if e != nil {
-		// panic(e)
 	}
 
 	session.Close()
 
-	if len(g.Members) > 0 {
-		for _, member := range g.Members {
-			member.SaveToNeo4j(driver)
+	for _, member := range g.Members {
+		member.SaveToNeo4j(driver)
+	}
+	g.saveMemberships(driver)
+}
+
+// saveMemberships links the members of the group to it with their current
+// membership details, and records the nicknames, avatars and group image in
+// use right now. Join and leave times come from the membership events.
+func (g *Group) saveMemberships(driver *database.Neo4j) {
+	session, err := driver.NewWriteSession()
+	if err != nil {
+		log.Panic(err)
+	}
+	defer session.Close()
+
+	members := make([]interface{}, len(g.Members))
+	now := int(time.Now().Unix())
+	seen := messageSightings{nicknames: sightings{}, avatars: sightings{}, groupImages: sightings{}}
+	for i, member := range g.Members {
+		members[i] = Properties(member)
+		seen.nicknames.add(member.UserID, g.ID, member.Nickname, now, "")
+		seen.avatars.add(member.UserID, "", member.ImageURL, now, "")
+	}
+	seen.groupImages.add("", g.ID, g.ImageURL, now, "")
+
+	_, err = session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(`UNWIND $members AS m
+		MATCH (n:Member{UserID: m.UserID}), (g:Group{ID: $groupID})
+		MERGE (n)-[r:MEMBER_OF]->(g)
+		SET r.nickname = m.Nickname, r.muted = m.Muted, r.autokicked = m.Autokicked`,
+			map[string]interface{}{"members": members, "groupID": g.ID})
+		if err != nil {
+			return nil, err
+		}
+		_, err = result.Consume()
+		if err != nil {
+			return nil, err
 		}
+		return nil, seen.save(tx)
+	})
+	if err != nil {
+		log.Panic(err)
 	}
 }
@@ -0,0 +1,108 @@
+package groupme
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// ImageService uploads pictures to GroupMe's image service. Only pictures
+// hosted there can be attached to messages.
+type ImageService struct {
+	// URL is the base URL of the image service.
+	URL    string
+	Token  string
+	Client *http.Client
+}
+
+type imageUpload struct {
+	Payload struct {
+		URL        string `json:"url"`
+		PictureURL string `json:"picture_url"`
+	} `json:"payload"`
+}
+
+// ImageService returns the image service for this GroupMe instance.
+func (g *GroupMe) ImageService() *ImageService {
+	return &ImageService{URL: g.ImageAPI, Token: g.APIKey, Client: g.Client}
+}
+
+// Upload uploads a picture, returning its URL on GroupMe's image CDN.
+func (s *ImageService) Upload(r io.Reader, contentType string) (string, error) {
+	request, err := http.NewRequest("POST", s.URL+"/pictures", r)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("X-Access-Token", s.Token)
+	request.Header.Set("Content-Type", contentType)
+
+	response, err := s.Client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return "", err
+	} else if response.StatusCode/100 != 2 {
+		return "", fmt.Errorf("image upload failed. Code: %d. Message: %s", response.StatusCode, body)
+	}
+
+	upload := imageUpload{}
+	err = json.Unmarshal(body, &upload)
+	if err != nil {
+		return "", err
+	}
+	if upload.Payload.URL != "" {
+		return upload.Payload.URL, nil
+	} else if upload.Payload.PictureURL != "" {
+		return upload.Payload.PictureURL, nil
+	}
+	return "", fmt.Errorf("image upload returned no URL: %s", body)
+}
+
+// UploadFile uploads a picture from the harddisk. The content type is taken
+// from the file extension, or sniffed from the contents when that fails.
+func (s *ImageService) UploadFile(name string) (string, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	contentType := mime.TypeByExtension(filepath.Ext(name))
+	if contentType == "" {
+		head := make([]byte, 512)
+		n, err := io.ReadFull(file, head)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return "", err
+		}
+		contentType = http.DetectContentType(head[:n])
+		_, err = file.Seek(0, io.SeekStart)
+		if err != nil {
+			return "", err
+		}
+	}
+	return s.Upload(file, contentType)
+}
+
+// ImageAttachments uploads pictures from the harddisk and returns them as
+// image attachments, ready to be sent with a message.
+func (g *GroupMe) ImageAttachments(names ...string) ([]Attachment, error) {
+	service := g.ImageService()
+	attachments := []Attachment{}
+	for _, name := range names {
+		url, err := service.UploadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("uploading %s: %v", name, err)
+		}
+		attachments = append(attachments, AttachmentImage{URL: url}.AsAttachment())
+	}
+	return attachments, nil
+}
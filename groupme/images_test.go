@@ -0,0 +1,78 @@
+package groupme
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeImageAndGroupServer accepts picture uploads and posted messages,
+// remembering the last posted message.
+func fakeImageAndGroupServer(t *testing.T, posted *messageCreate) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pictures":
+			if r.Header.Get("X-Access-Token") != "token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			body, _ := ioutil.ReadAll(r.Body)
+			if r.Header.Get("Content-Type") != "image/png" || string(body) != "png bytes" {
+				t.Errorf("bad upload %q %q", r.Header.Get("Content-Type"), body)
+			}
+			w.Write([]byte(`{"payload":{"url":"https://i.groupme.com/1x1.png.abc","picture_url":"https://i.groupme.com/1x1.png.abc"}}`))
+		case "/groups/1/messages":
+			json.NewDecoder(r.Body).Decode(posted)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(envelope{Meta: meta{Code: 201}, Response: messageCreated{Message: Message{ID: "5", Text: posted.Message.Text, Attachments: posted.Message.Attachments}}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestMessagesCreateWithImages(t *testing.T) {
+	posted := &messageCreate{}
+	server := fakeImageAndGroupServer(t, posted)
+	defer server.Close()
+	g := NewGroupMe("token")
+	g.API = server.URL
+	g.ImageAPI = server.URL
+
+	dir, err := ioutil.TempDir("", "images")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "cat.png")
+	err = ioutil.WriteFile(name, []byte("png bytes"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := g.MessagesCreateWithImages("1", "look", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.ID != "5" || posted.Message.SourceGUID == "" || posted.Message.Text != "look" {
+		t.Errorf("bad message %+v posted as %+v", m, posted)
+	}
+	if len(posted.Message.Attachments) != 1 || posted.Message.Attachments[0].Type != AttachmentTypeImage || posted.Message.Attachments[0].URL != "https://i.groupme.com/1x1.png.abc" {
+		t.Errorf("bad attachments %+v", posted.Message.Attachments)
+	}
+}
+
+func TestUploadRejected(t *testing.T) {
+	server := fakeImageAndGroupServer(t, &messageCreate{})
+	defer server.Close()
+	service := &ImageService{URL: server.URL, Token: "wrong", Client: http.DefaultClient}
+
+	_, err := service.Upload(nil, "image/png")
+	if err == nil {
+		t.Error("upload with a bad token succeeded")
+	}
+}
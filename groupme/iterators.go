@@ -0,0 +1,341 @@
+package groupme
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// maxMessagesPerPage is the largest page GroupMe will return for messages.
+const maxMessagesPerPage = 100
+
+// MessageBounds limits the messages returned by a message iterator. Messages
+// are returned newest first; zero values leave a bound open.
+type MessageBounds struct {
+	// BeforeID starts the iteration just below this message ID.
+	BeforeID string
+	// AfterID stops the iteration once this message ID is reached. The
+	// message itself is not returned.
+	AfterID string
+	// Since stops the iteration at the first message older than this time.
+	Since time.Time
+	// Until skips messages created at or after this time.
+	Until time.Time
+	// PerPage is the number of messages requested at a time.
+	PerPage int
+}
+
+// cursor tracks the position of a message iterator.
+type cursor struct {
+	bounds MessageBounds
+	// ascending iterations go oldest first, starting above the message they
+	// were created with.
+	ascending bool
+	// last is the ID of the last message reached, where the next page starts.
+	last  string
+	page  []pageEntry
+	index int
+	done  bool
+	err   error
+}
+
+// pageEntry is a message of the current page, as far as the cursor cares.
+type pageEntry struct {
+	id        string
+	createdAt int
+}
+
+// pageFetcher fetches the page of messages past the last one reached. It
+// keeps the messages to itself and returns their IDs and creation times, in
+// order.
+type pageFetcher func(ctx context.Context, last string) ([]pageEntry, error)
+
+func newCursor(bounds MessageBounds) cursor {
+	if bounds.PerPage <= 0 || bounds.PerPage > maxMessagesPerPage {
+		bounds.PerPage = maxMessagesPerPage
+	}
+	return cursor{bounds: bounds, last: bounds.BeforeID}
+}
+
+func newAscendingCursor(afterID string, perPage int) cursor {
+	c := newCursor(MessageBounds{PerPage: perPage})
+	c.ascending = true
+	c.last = afterID
+	return c
+}
+
+// check reports whether a message should be returned and whether the
+// iteration has run past its lower bound.
+func (c *cursor) check(id string, createdAt int) (yield, stop bool) {
+	if c.ascending {
+		return CompareIDs(id, c.last) > 0, false
+	}
+	if c.bounds.AfterID != "" && CompareIDs(id, c.bounds.AfterID) <= 0 {
+		return false, true
+	}
+	created := time.Unix(int64(createdAt), 0)
+	if !c.bounds.Since.IsZero() && created.Before(c.bounds.Since) {
+		return false, true
+	}
+	if !c.bounds.Until.IsZero() && !created.Before(c.bounds.Until) {
+		return false, false
+	}
+	return true, false
+}
+
+// next advances to the next message that should be returned, fetching pages
+// as needed. It returns the index of the message in the page last fetched,
+// or false when the iteration is over.
+func (c *cursor) next(ctx context.Context, fetch pageFetcher) (int, bool) {
+	for {
+		for c.index < len(c.page) {
+			i := c.index
+			c.index++
+
+			yield, stop := c.check(c.page[i].id, c.page[i].createdAt)
+			if yield || !c.ascending {
+				c.last = c.page[i].id
+			}
+			if stop {
+				c.done = true
+				c.page = nil
+				return 0, false
+			} else if yield {
+				return i, true
+			}
+		}
+		if c.done {
+			return 0, false
+		}
+
+		page, err := fetch(ctx, c.last)
+		if !c.finish(len(page), err) {
+			return 0, false
+		}
+		c.page, c.index = page, 0
+	}
+}
+
+// finish records the outcome of fetching a page. It returns false when the
+// iteration is over.
+func (c *cursor) finish(n int, err error) bool {
+	if err == ErrNotModified {
+		c.done = true
+		return false
+	} else if err != nil {
+		c.err = err
+		c.done = true
+		return false
+	} else if n == 0 {
+		c.done = true
+		return false
+	}
+	return true
+}
+
+// CompareIDs compares two GroupMe message IDs numerically, returning -1, 0 or
+// 1. IDs grow over time, so older messages have smaller IDs.
+func CompareIDs(a, b string) int {
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+// MessageIterator pages through the messages of a group, newest first unless
+// it was created by IterateMessagesAfter.
+type MessageIterator struct {
+	g       *GroupMe
+	groupID string
+	cursor  cursor
+	page    []Message
+	current Message
+}
+
+// IterateMessages returns an iterator over the messages of a group.
+func (g *GroupMe) IterateMessages(groupID string, bounds MessageBounds) *MessageIterator {
+	return &MessageIterator{g: g, groupID: groupID, cursor: newCursor(bounds)}
+}
+
+// IterateMessagesAfter returns an iterator over the messages of a group
+// posted after a message, oldest first. Stopping it at any point leaves every
+// message up to the last one returned behind it, which makes it suited to
+// catching up on new messages.
+func (g *GroupMe) IterateMessagesAfter(groupID, afterID string, perPage int) *MessageIterator {
+	return &MessageIterator{g: g, groupID: groupID, cursor: newAscendingCursor(afterID, perPage)}
+}
+
+// Next advances to the next message, fetching a new page when needed. It
+// returns false when there are no more messages or an error occurred.
+func (it *MessageIterator) Next(ctx context.Context) bool {
+	i, ok := it.cursor.next(ctx, func(ctx context.Context, last string) ([]pageEntry, error) {
+		var page []Message
+		var err error
+		if it.cursor.ascending {
+			page, err = it.g.messagesIndex(ctx, it.groupID, "", "", last, it.cursor.bounds.PerPage)
+		} else {
+			page, err = it.g.messagesIndex(ctx, it.groupID, last, "", "", it.cursor.bounds.PerPage)
+		}
+		it.page = page
+		entries := make([]pageEntry, len(page))
+		for j, m := range page {
+			entries[j] = pageEntry{id: m.ID, createdAt: m.CreatedAt}
+		}
+		return entries, err
+	})
+	if ok {
+		it.current = it.page[i]
+	}
+	return ok
+}
+
+// Value is the current message.
+func (it *MessageIterator) Value() Message {
+	return it.current
+}
+
+// Err is the error that stopped the iteration, if any.
+func (it *MessageIterator) Err() error {
+	return it.cursor.err
+}
+
+// BeforeID is the ID of the oldest message the iterator has reached, which
+// can be used to resume the iteration later. Iterators going oldest first
+// return the newest message they reached instead.
+func (it *MessageIterator) BeforeID() string {
+	return it.cursor.last
+}
+
+// DirectMessageIterator pages through the direct messages with another user,
+// newest first.
+type DirectMessageIterator struct {
+	g           *GroupMe
+	otherUserID string
+	cursor      cursor
+	page        []DirectMessage
+	current     DirectMessage
+}
+
+// IterateDirectMessages returns an iterator over the direct messages with
+// another user. GroupMe has a fixed page size for direct messages, so
+// bounds.PerPage is ignored.
+func (g *GroupMe) IterateDirectMessages(otherUserID string, bounds MessageBounds) *DirectMessageIterator {
+	return &DirectMessageIterator{g: g, otherUserID: otherUserID, cursor: newCursor(bounds)}
+}
+
+// Next advances to the next direct message, fetching a new page when needed.
+// It returns false when there are no more messages or an error occurred.
+func (it *DirectMessageIterator) Next(ctx context.Context) bool {
+	i, ok := it.cursor.next(ctx, func(ctx context.Context, last string) ([]pageEntry, error) {
+		page, err := it.g.directMessagesIndex(ctx, it.otherUserID, last, "")
+		it.page = page
+		entries := make([]pageEntry, len(page))
+		for j, m := range page {
+			entries[j] = pageEntry{id: m.ID, createdAt: m.CreatedAt}
+		}
+		return entries, err
+	})
+	if ok {
+		it.current = it.page[i]
+	}
+	return ok
+}
+
+// Value is the current direct message.
+func (it *DirectMessageIterator) Value() DirectMessage {
+	return it.current
+}
+
+// Err is the error that stopped the iteration, if any.
+func (it *DirectMessageIterator) Err() error {
+	return it.cursor.err
+}
+
+// BeforeID is the ID of the oldest direct message the iterator has reached.
+func (it *DirectMessageIterator) BeforeID() string {
+	return it.cursor.last
+}
+
+// GroupIterator pages through the groups of the user.
+type GroupIterator struct {
+	g             *GroupMe
+	perPage       int
+	page          int
+	includeFormer bool
+	former        bool
+	groups        []Group
+	current       Group
+	done          bool
+	err           error
+}
+
+// IterateGroups returns an iterator over every group of the user, optionally
+// followed by the groups the user has left.
+func (g *GroupMe) IterateGroups(perPage int, includeFormer bool) *GroupIterator {
+	if perPage <= 0 {
+		perPage = 100
+	}
+	return &GroupIterator{g: g, perPage: perPage, includeFormer: includeFormer}
+}
+
+// Next advances to the next group, fetching a new page when needed. It
+// returns false when there are no more groups or an error occurred.
+func (it *GroupIterator) Next(ctx context.Context) bool {
+	for {
+		if len(it.groups) > 0 {
+			it.current = it.groups[0]
+			it.groups = it.groups[1:]
+			return true
+		}
+		if it.done {
+			return false
+		}
+
+		groups := []Group{}
+		var err error
+		if !it.former {
+			it.page++
+			urlValues := map[string]string{
+				"page":     fmt.Sprint(it.page),
+				"per_page": fmt.Sprint(it.perPage),
+			}
+			_, err = it.g.groupMeRequestContext(ctx, "GET", "/groups", urlValues, &groups)
+		} else {
+			_, err = it.g.groupMeRequestContext(ctx, "GET", "/groups/former", nil, &groups)
+		}
+		if err == ErrNotModified {
+			groups, err = nil, nil
+		} else if err != nil {
+			it.err = err
+			it.done = true
+			return false
+		}
+
+		if it.former {
+			it.done = true
+		} else if len(groups) < it.perPage {
+			// This was the last page of current groups.
+			it.former = true
+			it.done = !it.includeFormer
+		}
+		it.groups = groups
+	}
+}
+
+// Value is the current group.
+func (it *GroupIterator) Value() Group {
+	return it.current
+}
+
+// Err is the error that stopped the iteration, if any.
+func (it *GroupIterator) Err() error {
+	return it.err
+}
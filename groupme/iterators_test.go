@@ -0,0 +1,120 @@
+package groupme
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fakeMessagesServer serves the messages of a group with IDs 1 to count, the
+// message with ID n having been created at unix time n*60. Paging past the
+// first message answers 304 like GroupMe does, and after_id pages oldest
+// first.
+func fakeMessagesServer(count int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		if after := r.URL.Query().Get("after_id"); after != "" {
+			index := MessagesIndex{Count: count}
+			bottom, _ := strconv.Atoi(after)
+			for id := bottom + 1; id <= count && len(index.Messages) < limit; id++ {
+				index.Messages = append(index.Messages, Message{ID: fmt.Sprint(id), CreatedAt: id * 60})
+			}
+			json.NewEncoder(w).Encode(envelope{Meta: meta{Code: 200}, Response: index})
+			return
+		}
+		top := count
+		if before := r.URL.Query().Get("before_id"); before != "" {
+			top, _ = strconv.Atoi(before)
+			top--
+		}
+		if top < 1 {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		index := MessagesIndex{Count: count}
+		for id := top; id >= 1 && len(index.Messages) < limit; id-- {
+			index.Messages = append(index.Messages, Message{ID: fmt.Sprint(id), CreatedAt: id * 60})
+		}
+		json.NewEncoder(w).Encode(envelope{Meta: meta{Code: 200}, Response: index})
+	}))
+}
+
+func collectIDs(t *testing.T, it *MessageIterator) []string {
+	ids := []string{}
+	for it.Next(context.Background()) {
+		ids = append(ids, it.Value().ID)
+	}
+	if it.Err() != nil {
+		t.Fatal(it.Err())
+	}
+	return ids
+}
+
+func TestMessageIteratorAll(t *testing.T) {
+	server := fakeMessagesServer(25)
+	defer server.Close()
+	g := NewGroupMe("token")
+	g.API = server.URL
+
+	ids := collectIDs(t, g.IterateMessages("1", MessageBounds{PerPage: 10}))
+	if len(ids) != 25 || ids[0] != "25" || ids[24] != "1" {
+		t.Errorf("got %v", ids)
+	}
+}
+
+func TestMessageIteratorAfter(t *testing.T) {
+	server := fakeMessagesServer(25)
+	defer server.Close()
+	g := NewGroupMe("token")
+	g.API = server.URL
+
+	it := g.IterateMessagesAfter("1", "12", 5)
+	ids := collectIDs(t, it)
+	if len(ids) != 13 || ids[0] != "13" || ids[12] != "25" {
+		t.Errorf("got %v", ids)
+	}
+	if it.BeforeID() != "25" {
+		t.Errorf("stopped at %s", it.BeforeID())
+	}
+}
+
+func TestMessageIteratorIDBounds(t *testing.T) {
+	server := fakeMessagesServer(25)
+	defer server.Close()
+	g := NewGroupMe("token")
+	g.API = server.URL
+
+	ids := collectIDs(t, g.IterateMessages("1", MessageBounds{BeforeID: "20", AfterID: "9", PerPage: 4}))
+	if len(ids) != 10 || ids[0] != "19" || ids[9] != "10" {
+		t.Errorf("got %v", ids)
+	}
+}
+
+func TestMessageIteratorTimeBounds(t *testing.T) {
+	server := fakeMessagesServer(25)
+	defer server.Close()
+	g := NewGroupMe("token")
+	g.API = server.URL
+
+	bounds := MessageBounds{
+		Since:   time.Unix(5*60, 0),
+		Until:   time.Unix(8*60, 0),
+		PerPage: 3,
+	}
+	ids := collectIDs(t, g.IterateMessages("1", bounds))
+	if len(ids) != 3 || ids[0] != "7" || ids[2] != "5" {
+		t.Errorf("got %v", ids)
+	}
+}
+
+func TestCompareIDs(t *testing.T) {
+	if CompareIDs("99", "100") != -1 || CompareIDs("100", "99") != 1 || CompareIDs("123", "123") != 0 {
+		t.Fail()
+	}
+}
@@ -0,0 +1,245 @@
+package groupme
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/neo4j/neo4j-go-driver/neo4j"
+	"patrickwthomas.net/groupme-graph/database"
+)
+
+// Link is a normalized URL shared in a message.
+type Link struct {
+	URL    string
+	Domain string
+}
+
+// linkPattern matches the URLs in the text of a message.
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"]+`)
+
+// trackingParameters are query parameters that only say where a link was
+// shared from, which are dropped so the same page is the same Link.
+var trackingParameters = map[string]bool{
+	"fbclid": true,
+	"gclid":  true,
+	"igshid": true,
+	"mc_cid": true,
+	"mc_eid": true,
+	"si":     true,
+}
+
+// linkAttachments are the attachment types whose URL is a link.
+var linkAttachments = map[string]bool{
+	AttachmentTypeVideo: true,
+	AttachmentTypeFile:  true,
+}
+
+// ExtractLinks finds the URLs in text, in order and without duplicates.
+func ExtractLinks(text string) []Link {
+	links := []Link{}
+	seen := map[string]bool{}
+	for _, raw := range linkPattern.FindAllString(text, -1) {
+		link, ok := NormalizeURL(trimLink(raw))
+		if ok && !seen[link.URL] {
+			seen[link.URL] = true
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// trimLink drops the punctuation a sentence puts after a URL. Closing
+// parentheses are kept when the URL opened them, like Wikipedia's do.
+func trimLink(raw string) string {
+	for len(raw) > 0 {
+		last := raw[len(raw)-1]
+		if last == ')' && strings.Count(raw, "(") >= strings.Count(raw, ")") {
+			break
+		}
+		if !strings.ContainsRune(".,;:!?'*)]}", rune(last)) {
+			break
+		}
+		raw = raw[:len(raw)-1]
+	}
+	return raw
+}
+
+// NormalizeURL puts a URL into the form it is saved in: https, a lower case
+// host without www. or a default port, no fragment, no tracking parameters
+// and the rest of the query sorted. ok is false for anything that is not a
+// web link.
+func NormalizeURL(raw string) (link Link, ok bool) {
+	if strings.HasPrefix(strings.ToLower(raw), "www.") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return Link{}, false
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+	u.Scheme = "https"
+	u.Host = host
+	u.User = nil
+	u.Fragment = ""
+	u.RawFragment = ""
+	if u.Path == "/" {
+		u.Path = ""
+		u.RawPath = ""
+	}
+
+	query := u.Query()
+	for key := range query {
+		if trackingParameters[strings.ToLower(key)] || strings.HasPrefix(strings.ToLower(key), "utm_") {
+			query.Del(key)
+		}
+	}
+	u.RawQuery = query.Encode()
+	u.ForceQuery = false
+
+	return Link{URL: u.String(), Domain: u.Hostname()}, true
+}
+
+// Links returns the links in the text and the video and file attachments of
+// a message.
+func (m Message) Links() []Link {
+	links := ExtractLinks(m.Text)
+	seen := map[string]bool{}
+	for _, l := range links {
+		seen[l.URL] = true
+	}
+	for _, a := range m.Attachments {
+		if !linkAttachments[a.Type] {
+			continue
+		}
+		link, ok := NormalizeURL(a.URL)
+		if ok && !seen[link.URL] {
+			seen[link.URL] = true
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// linkRow is a link shared in a message.
+type linkRow struct {
+	MessageID string
+	GroupID   string
+	UserID    string
+	CreatedAt int
+	URL       string
+	Domain    string
+}
+
+// linkRows are the CONTAINS_LINK and SHARED edges of a message. Links in
+// system messages are not shared by anyone, so they only get CONTAINS_LINK.
+func linkRows(m Message) []interface{} {
+	rows := []interface{}{}
+	for _, l := range m.Links() {
+		row := linkRow{MessageID: m.ID, GroupID: m.GroupID, CreatedAt: m.CreatedAt, URL: l.URL, Domain: l.Domain}
+		if !m.System {
+			row.UserID = m.UserID
+		}
+		rows = append(rows, Properties(row))
+	}
+	return rows
+}
+
+// saveLinksQuery links messages to the links in them, and their authors to
+// the links they shared, once per message.
+const saveLinksQuery = `UNWIND $rows AS r
+MATCH (msg:Message{ID: r.MessageID})
+MERGE (l:Link{url: r.URL}) SET l.domain = r.Domain
+MERGE (msg)-[:CONTAINS_LINK]->(l)
+WITH r, l WHERE r.UserID <> ""
+MERGE (m:Member{UserID: r.UserID})
+MERGE (m)-[s:SHARED{message: r.MessageID}]->(l)
+SET s.GroupID = r.GroupID, s.at = r.CreatedAt`
+
+// Relink extracts the links of messages that were saved before links were,
+// batchSize messages at a time. Only messages without a CONTAINS_LINK edge
+// that have text or a video or file attachment are read, so messages that
+// already have links are left as they are. It returns the number of messages
+// read.
+func Relink(driver *database.Neo4j, batchSize int) (int, error) {
+	total := 0
+	last := ""
+	for {
+		messages, err := loadLinkMessages(driver, last, batchSize)
+		if err != nil || len(messages) == 0 {
+			return total, err
+		}
+		rows := []interface{}{}
+		for _, m := range messages {
+			rows = append(rows, linkRows(m)...)
+		}
+
+		session, err := driver.NewWriteSession()
+		if err != nil {
+			return total, err
+		}
+		_, err = session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+			return nil, runRows(tx, saveLinksQuery, rows)
+		})
+		session.Close()
+		if err != nil {
+			return total, err
+		}
+		total += len(messages)
+		// Messages without links still match after the write, so pages follow
+		// the IDs rather than an offset.
+		last = messages[len(messages)-1].ID
+	}
+}
+
+// loadLinkMessages loads the text and link attachments of the next page of
+// messages without links after the ID last, in order of ID.
+func loadLinkMessages(driver *database.Neo4j, last string, limit int) ([]Message, error) {
+	session, err := driver.NewReadSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	result, err := session.Run(`MATCH (m:Message)
+	WHERE m.ID > $last AND NOT (m)-[:CONTAINS_LINK]->()
+		AND (coalesce(m.Text, "") <> ""
+			OR size([(m)-[:HAS_ATTACHMENT]->(a:Attachment) WHERE a.Type IN $types | a]) > 0)
+	WITH m ORDER BY m.ID LIMIT $limit
+	OPTIONAL MATCH (m)-[:HAS_ATTACHMENT]->(a:Attachment) WHERE a.Type IN $types
+	RETURN m.ID, coalesce(m.GroupID, ""), coalesce(m.UserID, ""), coalesce(m.CreatedAt, 0),
+		coalesce(m.Text, ""), coalesce(m.System, false), [a IN collect(a) | [a.Type, coalesce(a.URL, "")]]`,
+		map[string]interface{}{"last": last, "limit": limit, "types": []interface{}{AttachmentTypeVideo, AttachmentTypeFile}})
+	if err != nil {
+		return nil, err
+	}
+	messages := []Message{}
+	for result.Next() {
+		values := result.Record().Values()
+		m := Message{}
+		m.ID, _ = values[0].(string)
+		m.GroupID, _ = values[1].(string)
+		m.UserID, _ = values[2].(string)
+		createdAt, _ := values[3].(int64)
+		m.CreatedAt = int(createdAt)
+		m.Text, _ = values[4].(string)
+		m.System, _ = values[5].(bool)
+		attachments, _ := values[6].([]interface{})
+		for _, value := range attachments {
+			pair, _ := value.([]interface{})
+			if len(pair) != 2 {
+				continue
+			}
+			a := Attachment{}
+			a.Type, _ = pair[0].(string)
+			a.URL, _ = pair[1].(string)
+			m.Attachments = append(m.Attachments, a)
+		}
+		messages = append(messages, m)
+	}
+	return messages, result.Err()
+}
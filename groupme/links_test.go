@@ -0,0 +1,69 @@
+package groupme
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	cases := map[string]string{
+		"http://WWW.Example.com/":                                    "https://example.com",
+		"https://example.com:443/a?b=2&a=1#top":                      "https://example.com/a?a=1&b=2",
+		"https://youtu.be/abc?si=xyz&t=30":                           "https://youtu.be/abc?t=30",
+		"https://news.site/story?utm_source=x&UTM_MEDIUM=y&fbclid=z": "https://news.site/story",
+		"www.example.com/path":                                       "https://example.com/path",
+		"https://example.com:8080/":                                  "https://example.com:8080",
+	}
+	for raw, want := range cases {
+		link, ok := NormalizeURL(raw)
+		if !ok || link.URL != want {
+			t.Errorf("NormalizeURL(%q) = %q, %v, want %q", raw, link.URL, ok, want)
+		}
+	}
+	for _, raw := range []string{"mailto:a@example.com", "ftp://example.com", "https://"} {
+		if _, ok := NormalizeURL(raw); ok {
+			t.Errorf("NormalizeURL(%q) is a link", raw)
+		}
+	}
+	link, _ := NormalizeURL("https://www.example.com:8080/x")
+	if link.Domain != "example.com" {
+		t.Errorf("got domain %q", link.Domain)
+	}
+}
+
+func TestExtractLinks(t *testing.T) {
+	links := ExtractLinks(`See https://en.wikipedia.org/wiki/Go_(game), and (www.example.com/a). Also "http://example.com/a"! https://x.org/?utm_source=gm`)
+	urls := []string{}
+	for _, l := range links {
+		urls = append(urls, l.URL)
+	}
+	want := []string{"https://en.wikipedia.org/wiki/Go_(game)", "https://example.com/a", "https://x.org"}
+	if !reflect.DeepEqual(urls, want) {
+		t.Errorf("got %v, want %v", urls, want)
+	}
+	if len(ExtractLinks("no links here, just example.com")) != 0 {
+		t.Error("found a link without a scheme or www.")
+	}
+}
+
+func TestLinkRows(t *testing.T) {
+	m := Message{ID: "1", GroupID: "g", UserID: "u", CreatedAt: 10, Text: "https://example.com/v",
+		Attachments: []Attachment{
+			{Type: AttachmentTypeVideo, URL: "https://v.groupme.com/1/video.mp4"},
+			{Type: AttachmentTypeImage, URL: "https://i.groupme.com/1.png"},
+			{Type: AttachmentTypeFile, URL: "http://example.com/v"},
+		}}
+	rows := linkRows(m)
+	if len(rows) != 2 {
+		t.Fatalf("got %v", rows)
+	}
+	video := rows[1].(map[string]interface{})
+	if video["URL"] != "https://v.groupme.com/1/video.mp4" || video["Domain"] != "v.groupme.com" || video["UserID"] != "u" {
+		t.Errorf("bad row %v", video)
+	}
+
+	m.System = true
+	if rows := linkRows(m); rows[0].(map[string]interface{})["UserID"] != "" {
+		t.Errorf("system message shared by %v", rows[0])
+	}
+}
@@ -78,7 +78,9 @@ func (g *GroupMe) MembersUpdate(groupID, nickname string) Member {
 	return *result
 }
 
-// SaveToNeo4j saves the current member into the database.
+// SaveToNeo4j saves the current member into the database. Members are shared
+// by every group they are in, so their nickname history is kept in Nickname
+// nodes instead.
 func (m *Member) SaveToNeo4j(driver *database.Neo4j) {
 	session, err := driver.NewWriteSession()
 	if err != nil {
@@ -86,11 +88,13 @@ func (m *Member) SaveToNeo4j(driver *database.Neo4j) {
 	}
 	defer session.Close()
 
-	result, err := session.Run(fmt.Sprintf("MERGE (n:Member{%s}) ON MATCH SET n.Nickname=\"%s\"", Melt(*m), quoteEscape(m.Nickname)), map[string]interface{}{})
-	e := result.Err()
+	result, err := session.Run("MERGE (n:Member{UserID: $member.UserID}) SET n += $member",
+		map[string]interface{}{"member": Properties(*m)})
+	if err != nil {
+		log.Panic(err)
+	}
+	_, err = result.Consume()
 	if err != nil {
 		log.Panic(err)
-	} else if e != nil {
-		// panic(e)
 	}
 }
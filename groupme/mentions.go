@@ -0,0 +1,78 @@
+package groupme
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// FindMentions finds the members mentioned as @nickname in a text. Nicknames
+// match regardless of case, and a mention must not touch a letter or digit on
+// either side, so e-mail addresses are left alone. When nicknames share a
+// prefix, the longest one wins.
+func FindMentions(text string, members []Member) []Mention {
+	candidates := []Member{}
+	for _, m := range members {
+		if m.Nickname != "" && m.UserID != "" {
+			candidates = append(candidates, m)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return len(candidates[i].Nickname) > len(candidates[j].Nickname)
+	})
+
+	mentions := []Mention{}
+	for i := 0; i < len(text); {
+		if text[i] != '@' || afterWord(text, i) {
+			i++
+			continue
+		}
+		end := -1
+		for _, m := range candidates {
+			end = matchNickname(text, i+1, m.Nickname)
+			if end != -1 {
+				mentions = append(mentions, Mention{UserID: m.UserID, Start: i, End: end})
+				break
+			}
+		}
+		if end == -1 {
+			i++
+		} else {
+			i = end
+		}
+	}
+	return mentions
+}
+
+// afterWord reports whether the character before i is a letter or digit.
+func afterWord(text string, i int) bool {
+	previous, _ := utf8.DecodeLastRuneInString(text[:i])
+	return i > 0 && (unicode.IsLetter(previous) || unicode.IsDigit(previous))
+}
+
+// matchNickname returns where the nickname ends if the text has it at start,
+// or -1.
+func matchNickname(text string, start int, nickname string) int {
+	end := start
+	for _, r := range nickname {
+		if end >= len(text) {
+			return -1
+		}
+		t, size := utf8.DecodeRuneInString(text[end:])
+		if t != r && !strings.EqualFold(string(t), string(r)) {
+			return -1
+		}
+		end += size
+	}
+	if next, _ := utf8.DecodeRuneInString(text[end:]); end < len(text) && (unicode.IsLetter(next) || unicode.IsDigit(next)) {
+		return -1
+	}
+	return end
+}
+
+// MentionMembers builds the mentions attachment for the members mentioned as
+// @nickname in a text, e.g. with the members from GroupsShow.
+func MentionMembers(text string, members []Member) (AttachmentMentions, error) {
+	return NewMentions(text, FindMentions(text, members)...)
+}
@@ -0,0 +1,112 @@
+package groupme
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+	"unicode/utf16"
+)
+
+var mentionMembers = []Member{
+	{UserID: "1", Nickname: "Jo"},
+	{UserID: "2", Nickname: "Jo Kim"},
+	{UserID: "3", Nickname: "Émile"},
+	{UserID: "4", Nickname: "😀 Kid"},
+	{UserID: "5", Nickname: "𝔉ancy"},
+}
+
+func TestFindMentions(t *testing.T) {
+	cases := []struct {
+		text  string
+		users []string
+	}{
+		{"@Jo hi", []string{"1"}},
+		{"@jo kim, hi", []string{"2"}},
+		{"@Joanna hi", []string{}},
+		{"hey @émile and @😀 Kid!", []string{"3", "4"}},
+		{"@𝔉ancy,@Jo", []string{"5", "1"}},
+		{"mail jo@example.com", []string{}},
+		{"mail sam@jo.com", []string{}},
+	}
+
+	for _, c := range cases {
+		users := []string{}
+		for _, m := range FindMentions(c.text, mentionMembers) {
+			users = append(users, m.UserID)
+		}
+		if !reflect.DeepEqual(users, c.users) {
+			t.Errorf("%q: got %v, want %v", c.text, users, c.users)
+		}
+	}
+}
+
+// mentionText is a random text mentioning random members between random
+// filler, which can hold emoji and other characters outside the BMP.
+type mentionText struct {
+	Text  string
+	Users []string
+}
+
+func (mentionText) Generate(r *rand.Rand, size int) reflect.Value {
+	runes := []rune("ab Zé😀𝔉\n.,漢🏳️‍🌈")
+	filler := func() string {
+		b := strings.Builder{}
+		for i := r.Intn(size + 1); i > 0; i-- {
+			b.WriteRune(runes[r.Intn(len(runes))])
+		}
+		return b.String()
+	}
+
+	t := mentionText{Text: filler(), Users: []string{}}
+	for i := r.Intn(5); i > 0; i-- {
+		m := mentionMembers[r.Intn(len(mentionMembers))]
+		t.Text += ". @" + m.Nickname + ". " + filler()
+		t.Users = append(t.Users, m.UserID)
+	}
+	return reflect.ValueOf(t)
+}
+
+func TestMentionMembersQuick(t *testing.T) {
+	check := func(c mentionText) bool {
+		a, err := MentionMembers(c.Text, mentionMembers)
+		if err != nil || !reflect.DeepEqual(a.UserIDs, c.Users) {
+			return false
+		}
+		// Every locus must cut @nickname out of the text in UTF-16.
+		units := utf16.Encode([]rune(c.Text))
+		for i, locus := range a.Loci {
+			mentioned := string(utf16.Decode(units[locus[0] : locus[0]+locus[1]]))
+			nickname := ""
+			for _, m := range mentionMembers {
+				if m.UserID == a.UserIDs[i] {
+					nickname = m.Nickname
+				}
+			}
+			if mentioned != "@"+nickname {
+				return false
+			}
+		}
+		return true
+	}
+	err := quick.Check(check, &quick.Config{MaxCount: 500})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNewMentionsQuick(t *testing.T) {
+	// Mentioning a whole random text must span all of it in UTF-16.
+	check := func(text string) bool {
+		if text == "" {
+			return true
+		}
+		a, err := NewMentions(text, Mention{UserID: "1", Start: 0, End: len(text)})
+		return err == nil && a.Loci[0][0] == 0 && a.Loci[0][1] == len(utf16.Encode([]rune(text)))
+	}
+	err := quick.Check(check, nil)
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -1,9 +1,17 @@
 package groupme
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/neo4j/neo4j-go-driver/neo4j"
 	"patrickwthomas.net/groupme-graph/database"
 )
 
@@ -25,43 +33,21 @@ type Message struct {
 	Text        string       `json:"text"`
 	System      bool         `json:"system"`
 	FavoritedBy []string     `json:"favorited_by"`
+	Reactions   []Reaction   `json:"reactions,omitempty"`
 	Attachments []Attachment `json:"attachments"`
-}
-
-// Attachment is the base struct for all attachments. Does nothing on its own.
-type Attachment struct {
-	Type string `json:"type"`
-}
-
-// AttachmentImage is a attachment containing a single image.
-type AttachmentImage struct {
-	Attachment
-	URL string `json:"url"`
-}
-
-// AttachmentLocation contains a location.
-type AttachmentLocation struct {
-	Attachment
-	Lat  string `json:"lat"`
-	Lng  string `json:"lng"`
-	Name string `json:"name"`
-}
-
-// AttachmentSplit is unknown.
-type AttachmentSplit struct {
-	Attachment
-	Token string `json:"token"`
-}
-
-// AttachmentEmoji attaches a GroupMe emoji.
-type AttachmentEmoji struct {
-	Attachment
-	Placeholder string  `json:"placeholder"`
-	Charmap     [][]int `json:"charmap"`
+	Event       *Event       `json:"event,omitempty"`
 }
 
 // MessagesIndex gets the groups index from GroupMe.
 func (g *GroupMe) MessagesIndex(groupID, beforeID, sinceID, afterID string, limit int) []Message {
+	messages, err := g.messagesIndex(context.Background(), groupID, beforeID, sinceID, afterID, limit)
+	if err != nil && err != ErrNotModified {
+		log.Panic(err)
+	}
+	return messages
+}
+
+func (g *GroupMe) messagesIndex(ctx context.Context, groupID, beforeID, sinceID, afterID string, limit int) ([]Message, error) {
 	messages := &MessagesIndex{}
 	urlValues := map[string]string{
 		"limit": fmt.Sprint(limit),
@@ -75,26 +61,233 @@ func (g *GroupMe) MessagesIndex(groupID, beforeID, sinceID, afterID string, limi
 		urlValues["after_id"] = afterID
 	}
 
-	_, err := g.groupMeRequest("GET", fmt.Sprintf("/groups/%s/messages", groupID), urlValues, messages)
+	_, err := g.groupMeRequestContext(ctx, "GET", fmt.Sprintf("/groups/%s/messages", groupID), urlValues, messages)
+	return messages.Messages, err
+}
+
+// SaveToNeo4j saves the current message into the database.
+func (m *Message) SaveToNeo4j(driver *database.Neo4j) {
+	err := SaveMessagesToNeo4j(driver, []Message{*m})
 	if err != nil {
 		log.Panic(err)
 	}
-	return messages.Messages
 }
 
-// SaveToNeo4j saves the current message into the database.
-func (m *Message) SaveToNeo4j(driver *database.Neo4j) {
+// MaxMessageLength is the most characters GroupMe accepts in a message.
+const MaxMessageLength = 1000
+
+// createAttempts is how many times a new message is sent before giving up
+// when GroupMe cannot be reached, fails or asks to slow down.
+const createAttempts = 3
+
+// createBackoff is how long to wait before the first retry of a new message.
+// The wait doubles with every retry.
+var createBackoff = 500 * time.Millisecond
+
+// Errors returned when a message cannot be sent as is.
+var (
+	ErrMessageEmpty   = errors.New("groupme: message has no text and no attachments")
+	ErrMessageTooLong = fmt.Errorf("groupme: message is longer than %d characters", MaxMessageLength)
+)
+
+type messageCreate struct {
+	Message struct {
+		SourceGUID  string       `json:"source_guid"`
+		Text        string       `json:"text"`
+		Attachments []Attachment `json:"attachments"`
+	} `json:"message"`
+}
+
+type messageCreated struct {
+	Message Message `json:"message"`
+}
+
+// MessagesCreate posts a message to a group and returns it as GroupMe saved it.
+// The message gets a new source GUID, so GroupMe ignores the retries of a
+// request that went through but whose answer was lost.
+func (g *GroupMe) MessagesCreate(groupID, text string, attachments ...MessageAttachment) (Message, error) {
+	return g.MessagesCreateContext(context.Background(), groupID, text, attachments...)
+}
+
+// MessagesCreateContext is MessagesCreate, giving up on retries when the
+// context is done.
+func (g *GroupMe) MessagesCreateContext(ctx context.Context, groupID, text string, attachments ...MessageAttachment) (Message, error) {
+	values := messageCreate{}
+	values.Message.SourceGUID = NewSourceGUID()
+	values.Message.Text = text
+	values.Message.Attachments = convertAttachments(attachments)
+	err := validateMessage(text, values.Message.Attachments)
+	if err != nil {
+		return Message{}, err
+	}
+
+	result := &messageCreated{}
+	err = retryCreate(ctx, func() (meta, error) {
+		return g.groupMeRequestPostObjectContext(ctx, fmt.Sprintf("/groups/%s/messages", groupID), values, result)
+	})
+	return result.Message, err
+}
+
+// MessagesCreateWithImages uploads pictures from the harddisk and posts them
+// to a group with some text.
+func (g *GroupMe) MessagesCreateWithImages(groupID, text string, files ...string) (Message, error) {
+	attachments, err := g.ImageAttachments(files...)
+	if err != nil {
+		return Message{}, err
+	}
+	return g.MessagesCreate(groupID, text, rawAttachments(attachments)...)
+}
+
+func convertAttachments(attachments []MessageAttachment) []Attachment {
+	converted := make([]Attachment, len(attachments))
+	for i, a := range attachments {
+		converted[i] = a.AsAttachment()
+	}
+	return converted
+}
+
+func rawAttachments(attachments []Attachment) []MessageAttachment {
+	raw := make([]MessageAttachment, len(attachments))
+	for i, a := range attachments {
+		raw[i] = a
+	}
+	return raw
+}
+
+func validateMessage(text string, attachments []Attachment) error {
+	if strings.TrimSpace(text) == "" && len(attachments) == 0 {
+		return ErrMessageEmpty
+	} else if utf8.RuneCountInString(text) > MaxMessageLength {
+		return ErrMessageTooLong
+	}
+	return nil
+}
+
+// retryCreate sends a new message until GroupMe answers, waiting longer
+// before every retry. Only requests that did not reach GroupMe, failed on its
+// side or were rate limited are retried.
+func retryCreate(ctx context.Context, send func() (meta, error)) error {
+	var err error
+	wait := createBackoff
+	for i := 0; i < createAttempts; i++ {
+		if i > 0 {
+			// Jitter keeps clients that failed together from retrying together.
+			timer := time.NewTimer(wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1)))
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			}
+			wait *= 2
+		}
+
+		var m meta
+		m, err = send()
+		if err == nil || !retryable(m.Code) || ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// retryable reports whether a request that failed with a status code is
+// worth sending again. A code of 0 means GroupMe never answered.
+func retryable(code int) bool {
+	return code == 0 || code == http.StatusTooManyRequests || code/100 == 5
+}
+
+// Queries saving a batch of messages and what was found in them.
+const (
+	saveMessagesQuery = `UNWIND $rows AS m
+MERGE (msg:Message{ID: m.ID}) SET msg += m`
+
+	saveAttachmentsQuery = `UNWIND $rows AS a
+MATCH (msg:Message{ID: a.MessageID})
+MERGE (n:Attachment{ID: a.ID}) SET n += a
+MERGE (msg)-[:HAS_ATTACHMENT]->(n)`
+
+	// Reactions can be taken back, so the reactions of a message are
+	// replaced rather than added to.
+	clearReactionsQuery = `UNWIND $rows AS m
+MATCH (:Member)-[r:REACTED]->(:Message{ID: m.ID}) DELETE r`
+
+	saveReactionsQuery = `UNWIND $rows AS r
+MATCH (msg:Message{ID: r.MessageID})
+MERGE (m:Member{UserID: r.UserID})
+MERGE (m)-[e:REACTED{emoji: r.Emoji}]->(msg) SET e.type = r.Type`
+
+	saveMembershipEventsQuery = `UNWIND $rows AS e
+MERGE (ev:MembershipEvent{ID: e.ID}) SET ev += e`
+)
+
+// SaveMessagesToNeo4j saves a batch of messages into the database in a single
+// transaction, along with their attachments, reactions and links, the membership
+// events found in system messages and the nicknames and avatars the messages
+// were sent with.
+func SaveMessagesToNeo4j(driver *database.Neo4j, messages []Message) error {
 	session, err := driver.NewWriteSession()
 	if err != nil {
-		log.Panic(err)
+		return err
 	}
 	defer session.Close()
 
-	result, err := session.Run(fmt.Sprintf(`MERGE (msg:Message{%s})`, Melt(*m)), map[string]interface{}{})
-	e := result.Err()
+	rows := make([]interface{}, len(messages))
+	attachments := []interface{}{}
+	reactions := []interface{}{}
+	links := []interface{}{}
+	events := []interface{}{}
+	for i, m := range messages {
+		rows[i] = Properties(m)
+		for j, a := range m.Attachments {
+			attachments = append(attachments, attachmentProperties(m, j, a))
+		}
+		reactions = append(reactions, reactionRows(m)...)
+		links = append(links, linkRows(m)...)
+		for _, e := range ParseSystemMessage(m) {
+			events = append(events, Properties(e))
+		}
+	}
+
+	_, err = session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		err := runRows(tx, saveMessagesQuery, rows)
+		if err != nil {
+			return nil, err
+		}
+		err = runRows(tx, saveAttachmentsQuery, attachments)
+		if err != nil {
+			return nil, err
+		}
+		err = runRows(tx, clearReactionsQuery, rows)
+		if err != nil {
+			return nil, err
+		}
+		err = runRows(tx, saveReactionsQuery, reactions)
+		if err != nil {
+			return nil, err
+		}
+		err = runRows(tx, saveLinksQuery, links)
+		if err != nil {
+			return nil, err
+		}
+		err = runRows(tx, saveMembershipEventsQuery, events)
+		if err != nil {
+			return nil, err
+		}
+		return nil, newMessageSightings(messages).save(tx)
+	})
+	return err
+}
+
+// runRows runs a query that unwinds $rows, skipping it when there are none.
+func runRows(tx neo4j.Transaction, query string, rows []interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	result, err := tx.Run(query, map[string]interface{}{"rows": rows})
 	if err != nil {
-		log.Panic(err)
-	} else if e != nil {
-		// panic(e)
+		return err
 	}
+	_, err = result.Consume()
+	return err
 }
@@ -0,0 +1,182 @@
+package groupme
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeCreateServer accepts posted messages after failing the first failures
+// requests with a status, recording every request it got.
+func fakeCreateServer(failures, status int, posted *[]messageCreate) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		create := messageCreate{}
+		json.NewDecoder(r.Body).Decode(&create)
+		*posted = append(*posted, create)
+		if len(*posted) <= failures {
+			w.WriteHeader(status)
+			if status != http.StatusTooManyRequests {
+				json.NewEncoder(w).Encode(envelope{Meta: meta{Code: status}})
+			}
+			return
+		}
+		message := Message{ID: "7", GroupID: "1", SourceGUID: create.Message.SourceGUID, Text: create.Message.Text, Attachments: create.Message.Attachments}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(envelope{Meta: meta{Code: 201}, Response: messageCreated{Message: message}})
+	}))
+}
+
+func TestMessagesCreate(t *testing.T) {
+	posted := []messageCreate{}
+	server := fakeCreateServer(0, 0, &posted)
+	defer server.Close()
+	g := NewGroupMe("token")
+	g.API = server.URL
+
+	text := "hi @Sam"
+	mentions, err := NewMentions(text, Mention{UserID: "2", Start: 3, End: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := g.MessagesCreate("1", text,
+		AttachmentLocation{Lat: "1.5", Lng: "2.5", Name: "Park"},
+		mentions,
+		AttachmentReply{ReplyID: "6"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.ID != "7" || m.SourceGUID == "" || m.SourceGUID != posted[0].Message.SourceGUID {
+		t.Errorf("bad message %+v", m)
+	}
+
+	a := posted[0].Message.Attachments
+	if len(a) != 3 || a[0].Type != AttachmentTypeLocation || a[1].Type != AttachmentTypeMentions || a[2].Type != AttachmentTypeReply {
+		t.Fatalf("bad attachments %+v", a)
+	}
+	if a[1].Loci[0][0] != 3 || a[1].Loci[0][1] != 4 || a[2].BaseReplyID != "6" {
+		t.Errorf("bad attachments %+v", a)
+	}
+}
+
+// fastRetries shortens the wait between retries for the rest of a test.
+func fastRetries(t *testing.T) {
+	backoff := createBackoff
+	createBackoff = time.Millisecond
+	t.Cleanup(func() { createBackoff = backoff })
+}
+
+func TestMessagesCreateRetriesWithSameGUID(t *testing.T) {
+	fastRetries(t)
+	posted := []messageCreate{}
+	server := fakeCreateServer(2, http.StatusBadGateway, &posted)
+	defer server.Close()
+	g := NewGroupMe("token")
+	g.API = server.URL
+
+	_, err := g.MessagesCreate("1", "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posted) != 3 || posted[0].Message.SourceGUID != posted[2].Message.SourceGUID {
+		t.Errorf("got requests %+v", posted)
+	}
+}
+
+func TestMessagesCreateRetriesRateLimits(t *testing.T) {
+	fastRetries(t)
+	posted := []messageCreate{}
+	server := fakeCreateServer(1, http.StatusTooManyRequests, &posted)
+	defer server.Close()
+	g := NewGroupMe("token")
+	g.API = server.URL
+
+	_, err := g.MessagesCreate("1", "hello")
+	if err != nil || len(posted) != 2 {
+		t.Errorf("got %v after %d requests", err, len(posted))
+	}
+}
+
+func TestMessagesCreateDoesNotRetryRejections(t *testing.T) {
+	fastRetries(t)
+	posted := []messageCreate{}
+	server := fakeCreateServer(1, http.StatusBadRequest, &posted)
+	defer server.Close()
+	g := NewGroupMe("token")
+	g.API = server.URL
+
+	_, err := g.MessagesCreate("1", "hello")
+	if err == nil || len(posted) != 1 {
+		t.Errorf("got %v after %d requests", err, len(posted))
+	}
+}
+
+func TestMessagesCreateStopsRetryingWhenDone(t *testing.T) {
+	posted := []messageCreate{}
+	server := fakeCreateServer(3, http.StatusServiceUnavailable, &posted)
+	defer server.Close()
+	g := NewGroupMe("token")
+	g.API = server.URL
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := g.MessagesCreateContext(ctx, "1", "hello")
+	if err != context.DeadlineExceeded || len(posted) != 1 {
+		t.Errorf("got %v after %d requests", err, len(posted))
+	}
+}
+
+func TestMessagesCreateValidates(t *testing.T) {
+	g := NewGroupMe("token")
+	g.API = "http://127.0.0.1:0"
+
+	_, err := g.MessagesCreate("1", " ")
+	if err != ErrMessageEmpty {
+		t.Errorf("empty message: got %v", err)
+	}
+	_, err = g.MessagesCreate("1", strings.Repeat("é", MaxMessageLength+1))
+	if err != ErrMessageTooLong {
+		t.Errorf("long message: got %v", err)
+	}
+}
+
+func TestNewMentionsUTF16(t *testing.T) {
+	text := "😀 @Jo"
+	a, err := NewMentions(text, Mention{UserID: "2", Start: strings.Index(text, "@"), End: len(text)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Loci[0][0] != 3 || a.Loci[0][1] != 3 {
+		t.Errorf("got loci %v", a.Loci)
+	}
+	_, err = NewMentions(text, Mention{UserID: "2", Start: 1, End: 3})
+	if err == nil {
+		t.Error("mention splitting a character accepted")
+	}
+}
+
+func TestTypedAttachments(t *testing.T) {
+	image := AttachmentImage{}
+	err := json.Unmarshal([]byte(`{"type": "image", "url": "https://i.groupme.com/a.png"}`), &image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image.Type != AttachmentTypeImage || image.URL != "https://i.groupme.com/a.png" {
+		t.Errorf("got %+v", image)
+	}
+	if a := image.AsAttachment(); a.Type != AttachmentTypeImage || a.URL != image.URL {
+		t.Errorf("converted to %+v", a)
+	}
+
+	location := AttachmentLocation{Attachment: Attachment{Type: AttachmentTypeLocation}, Lat: "1", Lng: "2", Name: "here"}
+	out, err := json.Marshal(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"type":"location","lat":"1","lng":"2","name":"here"}` {
+		t.Errorf("got %s", out)
+	}
+}
@@ -0,0 +1,22 @@
+package groupme
+
+// saveNicknamesQuery records sightings of nicknames. Each sighting widens the
+// first_seen and last_seen range of the nickname in its group.
+const saveNicknamesQuery = `UNWIND $rows AS s
+MERGE (n:Nickname{UserID: s.UserID, GroupID: s.GroupID, value: s.Value})
+ON CREATE SET n.first_seen = s.FirstSeen, n.last_seen = s.LastSeen
+ON MATCH SET n.first_seen = CASE WHEN s.FirstSeen < n.first_seen THEN s.FirstSeen ELSE n.first_seen END,
+	n.last_seen = CASE WHEN s.LastSeen > n.last_seen THEN s.LastSeen ELSE n.last_seen END`
+
+// addMessage records the nickname a message was sent under, and the new
+// nickname of a rename event.
+func (s sightings) addMessage(m Message) {
+	if !m.System && m.UserID != "" && m.GroupID != "" {
+		s.add(m.UserID, m.GroupID, m.Name, m.CreatedAt, m.ID)
+	}
+	for _, e := range ParseSystemMessage(m) {
+		if e.Type == MembershipRenamed && e.SubjectUserID != "" {
+			s.add(e.SubjectUserID, e.GroupID, e.Nickname, e.CreatedAt, m.ID)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package groupme
+
+import "testing"
+
+func TestNicknameSightings(t *testing.T) {
+	messages := []Message{
+		{ID: "3", UserID: "1", GroupID: "g", Name: "Pat", CreatedAt: 300},
+		{ID: "1", UserID: "1", GroupID: "g", Name: "Pat", CreatedAt: 100},
+		{ID: "5", UserID: "1", GroupID: "g", Name: "Patrick", CreatedAt: 500},
+		{ID: "2", UserID: "1", GroupID: "h", Name: "Pat", CreatedAt: 200},
+		{ID: "4", UserID: "system", GroupID: "g", Name: "GroupMe", System: true, CreatedAt: 400, Text: "Pat changed name to Patrick"},
+	}
+
+	nicknames := sightings{}
+	for _, m := range messages {
+		nicknames.addMessage(m)
+	}
+	if len(nicknames) != 3 {
+		t.Fatalf("got %d sightings, want 3", len(nicknames))
+	}
+
+	pat := nicknames[sightingKey{UserID: "1", GroupID: "g", Value: "Pat"}]
+	if pat == nil || pat.FirstSeen != 100 || pat.LastSeen != 300 || pat.SourceMessageID != "1" {
+		t.Errorf("bad sighting %+v", pat)
+	}
+	if nicknames[sightingKey{UserID: "system", GroupID: "g", Value: "GroupMe"}] != nil {
+		t.Error("system messages should not be sightings")
+	}
+}
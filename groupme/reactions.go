@@ -0,0 +1,55 @@
+package groupme
+
+// Reaction types.
+const (
+	ReactionTypeEmoji   = "emoji"
+	ReactionTypeUnicode = "unicode"
+)
+
+// ReactionLike is the emoji of a like, which is all that messages sent
+// before reactions have in their favorited_by.
+const ReactionLike = "❤️"
+
+// Reaction is the users who reacted to a message with the same emoji.
+type Reaction struct {
+	Type    string   `json:"type"`
+	Code    string   `json:"code"`
+	UserIDs []string `json:"user_ids"`
+}
+
+// AllReactions returns the reactions to a message. Messages without the
+// reactions payload are liked by their favorited_by users.
+func (m Message) AllReactions() []Reaction {
+	if len(m.Reactions) > 0 {
+		return m.Reactions
+	}
+	if len(m.FavoritedBy) == 0 {
+		return nil
+	}
+	return []Reaction{{Type: ReactionTypeEmoji, Code: ReactionLike, UserIDs: m.FavoritedBy}}
+}
+
+// reactionRow is a single REACTED edge.
+type reactionRow struct {
+	MessageID string
+	UserID    string
+	Emoji     string
+	Type      string
+}
+
+// reactionRows are the REACTED edges of a message.
+func reactionRows(m Message) []interface{} {
+	rows := []interface{}{}
+	seen := map[reactionRow]bool{}
+	for _, r := range m.AllReactions() {
+		for _, userID := range r.UserIDs {
+			row := reactionRow{MessageID: m.ID, UserID: userID, Emoji: r.Code, Type: r.Type}
+			if userID == "" || r.Code == "" || seen[row] {
+				continue
+			}
+			seen[row] = true
+			rows = append(rows, Properties(row))
+		}
+	}
+	return rows
+}
@@ -0,0 +1,35 @@
+package groupme
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestReactionsDecode(t *testing.T) {
+	m := Message{}
+	err := json.Unmarshal([]byte(`{"id": "1", "favorited_by": ["2", "3"],
+		"reactions": [{"type": "emoji", "code": "❤️", "user_ids": ["2"]}, {"type": "unicode", "code": "😂", "user_ids": ["3", "2"]}]}`), &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := reactionRows(m)
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want 3", len(rows))
+	}
+	last := rows[2].(map[string]interface{})
+	if last["UserID"] != "2" || last["Emoji"] != "😂" || last["Type"] != ReactionTypeUnicode || last["MessageID"] != "1" {
+		t.Errorf("bad row %v", last)
+	}
+}
+
+func TestReactionsFavoritedBy(t *testing.T) {
+	m := Message{ID: "1", FavoritedBy: []string{"2", "3"}}
+	reactions := m.AllReactions()
+	if len(reactions) != 1 || reactions[0].Code != ReactionLike || len(reactions[0].UserIDs) != 2 {
+		t.Errorf("got %+v", reactions)
+	}
+	if (Message{ID: "1"}).AllReactions() != nil {
+		t.Error("message without likes has reactions")
+	}
+}
@@ -2,3 +2,6 @@ package groupme
 
 // GroupMeAPI is API URL for GroupMe.
 const GroupMeAPI = "https://api.groupme.com/v3"
+
+// GroupMeImageAPI is the URL of GroupMe's image service.
+const GroupMeImageAPI = "https://image.groupme.com"
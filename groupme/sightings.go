@@ -0,0 +1,107 @@
+package groupme
+
+import (
+	"github.com/neo4j/neo4j-go-driver/neo4j"
+)
+
+// Queries recording sightings of avatars and group images. Each sighting
+// widens the first_seen and last_seen range of what was seen, and an earlier
+// sighting from a message becomes the new source_message.
+const (
+	saveAvatarsQuery = `UNWIND $rows AS s
+MERGE (n:Avatar{UserID: s.UserID, url: s.Value})
+ON CREATE SET n.first_seen = s.FirstSeen, n.last_seen = s.LastSeen, n.source_message = s.SourceMessageID
+ON MATCH SET n.source_message = CASE WHEN s.FirstSeen < n.first_seen AND s.SourceMessageID <> "" THEN s.SourceMessageID ELSE n.source_message END,
+	n.first_seen = CASE WHEN s.FirstSeen < n.first_seen THEN s.FirstSeen ELSE n.first_seen END,
+	n.last_seen = CASE WHEN s.LastSeen > n.last_seen THEN s.LastSeen ELSE n.last_seen END`
+
+	saveGroupImagesQuery = `UNWIND $rows AS s
+MERGE (n:GroupImage{GroupID: s.GroupID, url: s.Value})
+ON CREATE SET n.first_seen = s.FirstSeen, n.last_seen = s.LastSeen, n.source_message = s.SourceMessageID
+ON MATCH SET n.source_message = CASE WHEN s.FirstSeen < n.first_seen AND s.SourceMessageID <> "" THEN s.SourceMessageID ELSE n.source_message END,
+	n.first_seen = CASE WHEN s.FirstSeen < n.first_seen THEN s.FirstSeen ELSE n.first_seen END,
+	n.last_seen = CASE WHEN s.LastSeen > n.last_seen THEN s.LastSeen ELSE n.last_seen END`
+)
+
+// sighting is a value (a nickname, an avatar URL...) that was in use between
+// two times. SourceMessageID is the message it was first seen in, if any.
+type sighting struct {
+	UserID          string
+	GroupID         string
+	Value           string
+	FirstSeen       int
+	LastSeen        int
+	SourceMessageID string
+}
+
+// sightingKey identifies what was seen.
+type sightingKey struct {
+	UserID  string
+	GroupID string
+	Value   string
+}
+
+// sightings collects the values seen at given times.
+type sightings map[sightingKey]*sighting
+
+func (s sightings) add(userID, groupID, value string, at int, messageID string) {
+	if userID == "system" || value == "" {
+		return
+	}
+	key := sightingKey{UserID: userID, GroupID: groupID, Value: value}
+	seen, ok := s[key]
+	if !ok {
+		seen = &sighting{UserID: userID, GroupID: groupID, Value: value, FirstSeen: at, LastSeen: at, SourceMessageID: messageID}
+		s[key] = seen
+	}
+	if at < seen.FirstSeen {
+		seen.FirstSeen = at
+		seen.SourceMessageID = messageID
+	}
+	if at > seen.LastSeen {
+		seen.LastSeen = at
+	}
+}
+
+// messageSightings are the nicknames, avatars and group images found in a
+// batch of messages.
+type messageSightings struct {
+	nicknames   sightings
+	avatars     sightings
+	groupImages sightings
+}
+
+func newMessageSightings(messages []Message) messageSightings {
+	s := messageSightings{nicknames: sightings{}, avatars: sightings{}, groupImages: sightings{}}
+	for _, m := range messages {
+		s.nicknames.addMessage(m)
+		if !m.System && m.UserID != "" {
+			s.avatars.add(m.UserID, "", m.AvatarURL, m.CreatedAt, m.ID)
+		}
+		if url := groupAvatarChange(m); url != "" {
+			s.groupImages.add("", m.GroupID, url, m.CreatedAt, m.ID)
+		}
+	}
+	return s
+}
+
+func (s messageSightings) save(tx neo4j.Transaction) error {
+	err := s.nicknames.save(tx, saveNicknamesQuery)
+	if err != nil {
+		return err
+	}
+	err = s.avatars.save(tx, saveAvatarsQuery)
+	if err != nil {
+		return err
+	}
+	return s.groupImages.save(tx, saveGroupImagesQuery)
+}
+
+// save writes the sightings within a transaction.
+func (s sightings) save(tx neo4j.Transaction, query string) error {
+	rows := []interface{}{}
+	for _, seen := range s {
+		rows = append(rows, Properties(*seen))
+	}
+	return runRows(tx, query, rows)
+}
@@ -0,0 +1,35 @@
+package groupme
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMessageSightings(t *testing.T) {
+	messages := []Message{
+		{ID: "3", UserID: "1", GroupID: "g", Name: "Pat", AvatarURL: "https://i.groupme.com/b", CreatedAt: 300},
+		{ID: "1", UserID: "1", GroupID: "g", Name: "Pat", AvatarURL: "https://i.groupme.com/a", CreatedAt: 100},
+		{ID: "5", UserID: "1", GroupID: "g", Name: "Patrick", AvatarURL: "https://i.groupme.com/b", CreatedAt: 500},
+		{ID: "2", UserID: "1", GroupID: "h", Name: "Pat", CreatedAt: 200},
+		{ID: "4", UserID: "system", GroupID: "g", Name: "GroupMe", System: true, CreatedAt: 400, Text: "Pat changed the group's avatar",
+			Event: &Event{Type: "group.avatar_change", Data: json.RawMessage(`{"avatar_url":"https://i.groupme.com/c"}`)}},
+	}
+
+	seen := newMessageSightings(messages)
+	if len(seen.nicknames) != 3 {
+		t.Fatalf("got %d nicknames, want 3", len(seen.nicknames))
+	}
+
+	if len(seen.avatars) != 2 {
+		t.Fatalf("got %d avatars, want 2", len(seen.avatars))
+	}
+	b := seen.avatars[sightingKey{UserID: "1", Value: "https://i.groupme.com/b"}]
+	if b == nil || b.FirstSeen != 300 || b.LastSeen != 500 || b.SourceMessageID != "3" {
+		t.Errorf("bad avatar %+v", b)
+	}
+
+	image := seen.groupImages[sightingKey{GroupID: "g", Value: "https://i.groupme.com/c"}]
+	if len(seen.groupImages) != 1 || image == nil || image.SourceMessageID != "4" {
+		t.Errorf("bad group images %+v", seen.groupImages)
+	}
+}
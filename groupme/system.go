@@ -0,0 +1,169 @@
+package groupme
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Membership event types.
+const (
+	MembershipAdded   = "ADDED"
+	MembershipRemoved = "REMOVED"
+	MembershipLeft    = "LEFT"
+	MembershipRenamed = "RENAMED"
+)
+
+// Event is the structured form of a system message. Older messages only
+// have the text.
+type Event struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// MembershipEvent is a change to the membership of a group, taken from a
+// system message. User IDs are only known when GroupMe sent them along.
+type MembershipEvent struct {
+	ID            string
+	Type          string
+	GroupID       string
+	MessageID     string
+	CreatedAt     int
+	ActorUserID   string
+	ActorName     string
+	SubjectUserID string
+	SubjectName   string
+	// Nickname is the new nickname of a RENAMED event.
+	Nickname string
+}
+
+// eventID is a user ID that GroupMe sends either as a number or a string.
+type eventID string
+
+func (id *eventID) UnmarshalJSON(b []byte) error {
+	*id = eventID(strings.Trim(string(b), `"`))
+	return nil
+}
+
+type eventUser struct {
+	ID       eventID `json:"id"`
+	Nickname string  `json:"nickname"`
+}
+
+type eventData struct {
+	AddedUsers  []eventUser `json:"added_users"`
+	AdderUser   eventUser   `json:"adder_user"`
+	RemoverUser eventUser   `json:"remover_user"`
+	RemovedUser eventUser   `json:"removed_user"`
+	User        eventUser   `json:"user"`
+	Name        string      `json:"name"`
+	AvatarURL   string      `json:"avatar_url"`
+}
+
+var (
+	addedPattern   = regexp.MustCompile(`^(.+?) added (.+) to the group\.?$`)
+	removedPattern = regexp.MustCompile(`^(.+?) removed (.+) from the group\.?$`)
+	leftPattern    = regexp.MustCompile(`^(.+) has left the group\.?$`)
+	joinedPattern  = regexp.MustCompile(`^(.+) has (?:re)?joined the group\.?$`)
+	renamedPattern = regexp.MustCompile(`^(.+?) changed (?:their )?(?:nick)?name to (.+?)\.?$`)
+	listSeparator  = regexp.MustCompile(`,? and |, `)
+)
+
+// ParseSystemMessage finds the membership events in a system message. Other
+// messages have none.
+func ParseSystemMessage(m Message) []MembershipEvent {
+	if !m.System {
+		return nil
+	}
+
+	base := MembershipEvent{GroupID: m.GroupID, MessageID: m.ID, CreatedAt: m.CreatedAt}
+	events := parseEvent(base, m.Event)
+	if events == nil {
+		events = parseText(base, strings.TrimSpace(m.Text))
+	}
+	for i := range events {
+		events[i].ID = fmt.Sprintf("%s:%d", m.ID, i)
+	}
+	return events
+}
+
+func parseEvent(base MembershipEvent, event *Event) []MembershipEvent {
+	if event == nil {
+		return nil
+	}
+	data := eventData{}
+	if json.Unmarshal(event.Data, &data) != nil {
+		return nil
+	}
+
+	withUsers := func(eventType string, actor, subject eventUser) MembershipEvent {
+		e := base
+		e.Type = eventType
+		e.ActorUserID, e.ActorName = string(actor.ID), actor.Nickname
+		e.SubjectUserID, e.SubjectName = string(subject.ID), subject.Nickname
+		return e
+	}
+
+	switch event.Type {
+	case "membership.announce.added":
+		events := []MembershipEvent{}
+		for _, user := range data.AddedUsers {
+			events = append(events, withUsers(MembershipAdded, data.AdderUser, user))
+		}
+		return events
+	case "membership.announce.joined", "membership.announce.rejoined":
+		return []MembershipEvent{withUsers(MembershipAdded, data.User, data.User)}
+	case "membership.notifications.removed":
+		return []MembershipEvent{withUsers(MembershipRemoved, data.RemoverUser, data.RemovedUser)}
+	case "membership.notifications.exited":
+		return []MembershipEvent{withUsers(MembershipLeft, data.RemovedUser, data.RemovedUser)}
+	case "membership.nickname_changed":
+		e := withUsers(MembershipRenamed, data.User, data.User)
+		e.Nickname = data.Name
+		return []MembershipEvent{e}
+	}
+	return nil
+}
+
+func parseText(base MembershipEvent, text string) []MembershipEvent {
+	withNames := func(eventType, actor, subject string) MembershipEvent {
+		e := base
+		e.Type = eventType
+		e.ActorName = actor
+		e.SubjectName = subject
+		return e
+	}
+
+	if match := addedPattern.FindStringSubmatch(text); match != nil {
+		events := []MembershipEvent{}
+		for _, name := range listSeparator.Split(match[2], -1) {
+			events = append(events, withNames(MembershipAdded, match[1], name))
+		}
+		return events
+	} else if match := removedPattern.FindStringSubmatch(text); match != nil {
+		return []MembershipEvent{withNames(MembershipRemoved, match[1], match[2])}
+	} else if match := leftPattern.FindStringSubmatch(text); match != nil {
+		return []MembershipEvent{withNames(MembershipLeft, match[1], match[1])}
+	} else if match := joinedPattern.FindStringSubmatch(text); match != nil {
+		return []MembershipEvent{withNames(MembershipAdded, match[1], match[1])}
+	} else if match := renamedPattern.FindStringSubmatch(text); match != nil {
+		e := withNames(MembershipRenamed, match[1], match[1])
+		e.Nickname = match[2]
+		return []MembershipEvent{e}
+	}
+	return nil
+}
+
+// groupAvatarChange returns the new group image of a system message that
+// changed it.
+func groupAvatarChange(m Message) string {
+	if !m.System || m.Event == nil || m.Event.Type != "group.avatar_change" {
+		return ""
+	}
+	data := eventData{}
+	if json.Unmarshal(m.Event.Data, &data) != nil {
+		return ""
+	}
+	return data.AvatarURL
+}
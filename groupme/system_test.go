@@ -0,0 +1,73 @@
+package groupme
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func systemMessage(text string) Message {
+	return Message{ID: "100", GroupID: "1", CreatedAt: 1600000000, System: true, Text: text}
+}
+
+func TestParseSystemMessageText(t *testing.T) {
+	cases := []struct {
+		text     string
+		types    []string
+		subjects []string
+		actor    string
+		nickname string
+	}{
+		{"Pat added Sam to the group.", []string{"ADDED"}, []string{"Sam"}, "Pat", ""},
+		{"Pat added Sam, Alex Kim, and Jo to the group.", []string{"ADDED", "ADDED", "ADDED"}, []string{"Sam", "Alex Kim", "Jo"}, "Pat", ""},
+		{"Pat added Sam and Jo to the group.", []string{"ADDED", "ADDED"}, []string{"Sam", "Jo"}, "Pat", ""},
+		{"Pat removed Sam from the group.", []string{"REMOVED"}, []string{"Sam"}, "Pat", ""},
+		{"Sam has left the group.", []string{"LEFT"}, []string{"Sam"}, "Sam", ""},
+		{"Sam has rejoined the group", []string{"ADDED"}, []string{"Sam"}, "Sam", ""},
+		{"Sam changed name to Samwise", []string{"RENAMED"}, []string{"Sam"}, "Sam", "Samwise"},
+		{"Sam changed nickname to Sam the Wise.", []string{"RENAMED"}, []string{"Sam"}, "Sam", "Sam the Wise"},
+		{"Pat changed the group's name to Towel", nil, nil, "", ""},
+	}
+
+	for _, c := range cases {
+		events := ParseSystemMessage(systemMessage(c.text))
+		if len(events) != len(c.types) {
+			t.Errorf("%q: got %d events, want %d", c.text, len(events), len(c.types))
+			continue
+		}
+		for i, e := range events {
+			if e.Type != c.types[i] || e.SubjectName != c.subjects[i] || e.ActorName != c.actor || e.Nickname != c.nickname {
+				t.Errorf("%q: bad event %+v", c.text, e)
+			}
+			if e.GroupID != "1" || e.CreatedAt != 1600000000 || e.MessageID != "100" {
+				t.Errorf("%q: event lost its message details %+v", c.text, e)
+			}
+		}
+	}
+}
+
+func TestParseSystemMessageEvent(t *testing.T) {
+	m := systemMessage("Pat added Sam to the group.")
+	m.Event = &Event{
+		Type: "membership.announce.added",
+		Data: json.RawMessage(`{"added_users":[{"id":2,"nickname":"Sam"},{"id":"3","nickname":"Jo"}],"adder_user":{"id":1,"nickname":"Pat"}}`),
+	}
+
+	events := ParseSystemMessage(m)
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	if events[0].SubjectUserID != "2" || events[1].SubjectUserID != "3" || events[0].ActorUserID != "1" {
+		t.Errorf("bad events %+v", events)
+	}
+	if events[0].ID == events[1].ID {
+		t.Error("events share an ID")
+	}
+}
+
+func TestParseSystemMessageNotSystem(t *testing.T) {
+	m := systemMessage("Pat added Sam to the group.")
+	m.System = false
+	if ParseSystemMessage(m) != nil {
+		t.Fail()
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"patrickwthomas.net/groupme-graph/media"
+)
+
+func downloadImagesCommand(args []string) {
+	flags := flag.NewFlagSet("download-images", flag.ExitOnError)
+	flags.Parse(args)
+
+	settings := loadSettings()
+	driver := connectNeo4j()
+	downloader := media.NewDownloader(media.NewStore(mediaDir(settings)))
+	rememberKnownFiles(driver, downloader.Store)
+
+	urls, err := media.PendingImages(driver)
+	if err != nil {
+		log.Panic(err)
+	}
+	fmt.Printf("Downloading %d images.\n", len(urls))
+
+	failed := 0
+	for _, url := range urls {
+		hash, path, err := downloader.Fetch(context.Background(), url, media.Info{})
+		if err != nil {
+			log.Printf("Skipping %s: %v", url, err)
+			failed++
+			continue
+		}
+		err = media.RecordImage(driver, url, hash, path)
+		if err != nil {
+			log.Panic(err)
+		}
+	}
+	fmt.Printf("Downloaded %d images, %d failed.\n", len(urls)-failed, failed)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"patrickwthomas.net/groupme-graph/export"
+	"patrickwthomas.net/groupme-graph/groupme"
+)
+
+func importCommand(args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: import <export.zip>...\n")
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	settings := readSettings()
+	driver := connectNeo4j()
+	store := newStore(settings, driver)
+
+	for _, name := range flags.Args() {
+		archive, err := export.Open(name)
+		if err != nil {
+			log.Panic(err)
+		}
+		fmt.Printf("Found %d conversations in %s.\n", len(archive.Conversations), name)
+
+		total, err := archive.Import(store, os.Stdout)
+		archive.Close()
+		if err != nil {
+			log.Panic(err)
+		}
+		fmt.Printf("Imported %d messages.\n", total)
+	}
+
+	groupme.Connect(driver)
+}
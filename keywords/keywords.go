@@ -0,0 +1,205 @@
+// Package keywords finds what groups talk about: the most frequent n-grams of
+// their messages, weighted by TF-IDF against other groups, members or months.
+package keywords
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Tokenizer splits message text into terms.
+type Tokenizer struct {
+	// Stopwords are left out of terms. Terms of several words may contain
+	// them, but not start or end with them.
+	Stopwords map[string]bool
+	// KeepEmoji keeps emoji as terms of their own instead of dropping them.
+	KeepEmoji bool
+	// MaxN is the longest n-gram counted.
+	MaxN int
+}
+
+// NewTokenizer creates a tokenizer counting words and pairs of words, with
+// the built-in English stopwords.
+func NewTokenizer() *Tokenizer {
+	t := &Tokenizer{Stopwords: map[string]bool{}, MaxN: 2}
+	t.AddStopwords(englishStopwords...)
+	return t
+}
+
+// AddStopwords adds stopwords, in any case.
+func (t *Tokenizer) AddStopwords(words ...string) {
+	for _, w := range words {
+		t.Stopwords[strings.ToLower(strings.TrimSpace(w))] = true
+	}
+}
+
+// urlPattern matches links, which are not words.
+var urlPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+
+// Words splits a text into lower case words, dropping links, numbers and
+// GroupMe emoji placeholders. Apostrophes stay inside words.
+func (t *Tokenizer) Words(text string) []string {
+	text = urlPattern.ReplaceAllString(text, " ")
+	words := []string{}
+	current := strings.Builder{}
+	flush := func() {
+		if current.Len() > 0 {
+			word := strings.Trim(current.String(), "'")
+			if word != "" && !isNumber(word) {
+				words = append(words, word)
+			}
+			current.Reset()
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case r == '\'' || r == '’':
+			current.WriteRune('\'')
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			current.WriteRune(r)
+		case unicode.Is(unicode.So, r) && r != '�':
+			flush()
+			if t.KeepEmoji {
+				words = append(words, string(r))
+			}
+		default:
+			flush()
+		}
+	}
+	flush()
+	return words
+}
+
+// Terms returns the n-grams of a text, up to MaxN words long.
+func (t *Tokenizer) Terms(text string) []string {
+	words := t.Words(text)
+	terms := []string{}
+	for n := 1; n <= t.MaxN; n++ {
+		for i := 0; i+n <= len(words); i++ {
+			gram := words[i : i+n]
+			if t.Stopwords[gram[0]] || t.Stopwords[gram[n-1]] {
+				continue
+			}
+			terms = append(terms, strings.Join(gram, " "))
+		}
+	}
+	return terms
+}
+
+func isNumber(word string) bool {
+	for _, r := range word {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Corpus counts the terms of a set of documents, such as the months of a
+// group or the members of a group.
+type Corpus struct {
+	tokenizer *Tokenizer
+	counts    map[string]map[string]int
+	totals    map[string]int
+}
+
+// NewCorpus creates an empty corpus.
+func NewCorpus(t *Tokenizer) *Corpus {
+	return &Corpus{tokenizer: t, counts: map[string]map[string]int{}, totals: map[string]int{}}
+}
+
+// Add adds the text of a message to a document.
+func (c *Corpus) Add(document, text string) {
+	if c.counts[document] == nil {
+		c.counts[document] = map[string]int{}
+	}
+	for _, term := range c.tokenizer.Terms(text) {
+		c.counts[document][term]++
+		c.totals[document]++
+	}
+}
+
+// Documents returns the names of the documents, sorted.
+func (c *Corpus) Documents() []string {
+	documents := []string{}
+	for d := range c.counts {
+		documents = append(documents, d)
+	}
+	sort.Strings(documents)
+	return documents
+}
+
+// Keyword is a term of a document with its weight.
+type Keyword struct {
+	Term  string
+	Count int
+	TFIDF float64
+}
+
+// Keywords returns the top terms of a document by TF-IDF. Terms are weighted
+// by their frequency in the document and how few other documents use them,
+// with smoothing so that a corpus of a single document ranks by frequency.
+// Terms used only once are left out.
+func (c *Corpus) Keywords(document string, top int) []Keyword {
+	n := float64(len(c.counts))
+	keywords := []Keyword{}
+	for term, count := range c.counts[document] {
+		if count < 2 {
+			continue
+		}
+		df := 0
+		for _, counts := range c.counts {
+			if counts[term] > 0 {
+				df++
+			}
+		}
+		tf := float64(count) / float64(c.totals[document])
+		idf := math.Log((1+n)/(1+float64(df))) + 1
+		keywords = append(keywords, Keyword{Term: term, Count: count, TFIDF: tf * idf})
+	}
+	sortKeywords(keywords, func(k Keyword) float64 { return k.TFIDF })
+	if top > 0 && len(keywords) > top {
+		keywords = keywords[:top]
+	}
+	return keywords
+}
+
+// Frequent returns the most frequent terms of a document of exactly n words.
+func (c *Corpus) Frequent(document string, n, top int) []Keyword {
+	keywords := []Keyword{}
+	for term, count := range c.counts[document] {
+		if strings.Count(term, " ")+1 == n {
+			keywords = append(keywords, Keyword{Term: term, Count: count})
+		}
+	}
+	sortKeywords(keywords, func(k Keyword) float64 { return float64(k.Count) })
+	if top > 0 && len(keywords) > top {
+		keywords = keywords[:top]
+	}
+	return keywords
+}
+
+func sortKeywords(keywords []Keyword, weight func(Keyword) float64) {
+	sort.Slice(keywords, func(i, j int) bool {
+		if weight(keywords[i]) != weight(keywords[j]) {
+			return weight(keywords[i]) > weight(keywords[j])
+		}
+		return keywords[i].Term < keywords[j].Term
+	})
+}
+
+// englishStopwords are common English words and chat filler.
+var englishStopwords = strings.Fields(`a about above after again against all am an and any are aren't as at
+be because been before being below between both but by can can't cannot could couldn't did didn't do does
+doesn't doing don't down during each few for from further get got had hadn't has hasn't have haven't having
+he he'd he'll he's her here here's hers herself him himself his how how's i i'd i'll i'm i've if in into is
+isn't it it's its itself just let's like me more most mustn't my myself no nor not now of off on once only or
+other ought our ours ourselves out over own same shan't she she'd she'll she's should shouldn't so some such
+than that that's the their theirs them themselves then there there's these they they'd they'll they're
+they've this those through to too under until up very was wasn't we we'd we'll we're we've were weren't what
+what's when when's where where's which while who who's whom why why's will with won't would wouldn't you
+you'd you'll you're you've your yours yourself yourselves also im dont cant thats u ur yeah yes ok okay oh
+lol lmao haha gonna wanna gotta go going know think one really s t`)
@@ -0,0 +1,59 @@
+package keywords
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWords(t *testing.T) {
+	tok := NewTokenizer()
+	words := tok.Words("Check https://example.com/a?b=1 — it's GREAT 👍 at 5pm, 2021 www.x.org")
+	want := []string{"check", "it's", "great", "at", "5pm"}
+	if !reflect.DeepEqual(words, want) {
+		t.Errorf("got %q, want %q", words, want)
+	}
+
+	tok.KeepEmoji = true
+	if words := tok.Words("nice👍"); !reflect.DeepEqual(words, []string{"nice", "👍"}) {
+		t.Errorf("got %q with emoji", words)
+	}
+}
+
+func TestTerms(t *testing.T) {
+	tok := NewTokenizer()
+	tok.AddStopwords("Pizza")
+	terms := tok.Terms("the board game night was a board game night, pizza")
+	want := []string{"board", "game", "night", "board", "game", "night",
+		"board game", "game night", "board game", "game night"}
+	if !reflect.DeepEqual(terms, want) {
+		t.Errorf("got %q, want %q", terms, want)
+	}
+}
+
+func TestKeywords(t *testing.T) {
+	c := NewCorpus(NewTokenizer())
+	c.Add("2021-03", "hiking trip saturday")
+	c.Add("2021-03", "the hiking trip is on")
+	c.Add("2021-03", "weather looks good for saturday")
+	c.Add("2021-04", "weather looks bad")
+	c.Add("2021-04", "weather is awful")
+
+	if docs := c.Documents(); !reflect.DeepEqual(docs, []string{"2021-03", "2021-04"}) {
+		t.Errorf("got documents %v", docs)
+	}
+
+	march := c.Keywords("2021-03", 3)
+	terms := []string{}
+	for _, k := range march {
+		terms = append(terms, k.Term)
+	}
+	// Weather is talked about every month, so it ranks below the trip.
+	if !reflect.DeepEqual(terms, []string{"hiking", "hiking trip", "saturday"}) {
+		t.Errorf("got %+v", march)
+	}
+
+	frequent := c.Frequent("2021-04", 1, 1)
+	if len(frequent) != 1 || frequent[0].Term != "weather" || frequent[0].Count != 2 {
+		t.Errorf("got %+v", frequent)
+	}
+}
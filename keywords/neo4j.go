@@ -0,0 +1,107 @@
+package keywords
+
+import (
+	"github.com/neo4j/neo4j-go-driver/neo4j"
+	"patrickwthomas.net/groupme-graph/database"
+)
+
+// Text is the text of a message and where it came from.
+type Text struct {
+	GroupID string
+	UserID  string
+	At      int
+	Text    string
+}
+
+// LoadTexts loads the text of the messages of a group, or of every group,
+// leaving out system messages.
+func LoadTexts(driver *database.Neo4j, groupID string) ([]Text, error) {
+	session, err := driver.NewReadSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	result, err := session.Run(`MATCH (m:Message)
+	WHERE ($group = "" OR m.GroupID = $group) AND coalesce(m.System, false) = false AND m.Text <> ""
+	RETURN m.GroupID, m.UserID, m.CreatedAt, m.Text`, map[string]interface{}{"group": groupID})
+	if err != nil {
+		return nil, err
+	}
+	texts := []Text{}
+	for result.Next() {
+		values := result.Record().Values()
+		t := Text{}
+		t.GroupID, _ = values[0].(string)
+		t.UserID, _ = values[1].(string)
+		at, _ := values[2].(int64)
+		t.At = int(at)
+		t.Text, _ = values[3].(string)
+		texts = append(texts, t)
+	}
+	return texts, result.Err()
+}
+
+// Queries replacing the keywords of a group during a period, or of a member
+// in a group.
+const (
+	clearGroupKeywordsQuery = `MATCH (:Group{ID: $group})-[r:DISCUSSED{period: $period, window_start: $start}]->(:Term) DELETE r`
+
+	saveGroupKeywordsQuery = `MATCH (g:Group{ID: $group})
+UNWIND $rows AS k
+MERGE (t:Term{value: k.term})
+CREATE (g)-[:DISCUSSED{period: $period, window_start: $start, label: $label, count: k.count, tfidf: k.tfidf}]->(t)`
+
+	clearMemberKeywordsQuery = `MATCH (:Member{UserID: $user})-[r:USED{GroupID: $group}]->(:Term) DELETE r`
+
+	saveMemberKeywordsQuery = `MATCH (m:Member{UserID: $user})
+UNWIND $rows AS k
+MERGE (t:Term{value: k.term})
+CREATE (m)-[:USED{GroupID: $group, count: k.count, tfidf: k.tfidf}]->(t)`
+)
+
+// SaveGroupKeywords replaces the keywords of a group during a window with
+// (:Group)-[:DISCUSSED]->(:Term) edges. The window of all time starts at 0.
+func SaveGroupKeywords(driver *database.Neo4j, groupID, period string, start int, label string, keywords []Keyword) error {
+	params := map[string]interface{}{"group": groupID, "period": period, "start": start, "label": label, "rows": keywordRows(keywords)}
+	return replace(driver, clearGroupKeywordsQuery, saveGroupKeywordsQuery, params)
+}
+
+// SaveMemberKeywords replaces the keywords of a member in a group with
+// (:Member)-[:USED]->(:Term) edges.
+func SaveMemberKeywords(driver *database.Neo4j, groupID, userID string, keywords []Keyword) error {
+	params := map[string]interface{}{"group": groupID, "user": userID, "rows": keywordRows(keywords)}
+	return replace(driver, clearMemberKeywordsQuery, saveMemberKeywordsQuery, params)
+}
+
+func keywordRows(keywords []Keyword) []interface{} {
+	rows := make([]interface{}, len(keywords))
+	for i, k := range keywords {
+		rows[i] = map[string]interface{}{"term": k.Term, "count": k.Count, "tfidf": k.TFIDF}
+	}
+	return rows
+}
+
+// replace runs a query clearing edges and one writing them in a transaction.
+func replace(driver *database.Neo4j, clear, save string, params map[string]interface{}) error {
+	session, err := driver.NewWriteSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	_, err = session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		for _, query := range []string{clear, save} {
+			result, err := tx.Run(query, params)
+			if err != nil {
+				return nil, err
+			}
+			_, err = result.Consume()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+	return err
+}
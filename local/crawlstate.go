@@ -0,0 +1,70 @@
+package local
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"patrickwthomas.net/groupme-graph/crawl"
+)
+
+const crawlStateFileDir = "./crawl_state.json"
+
+// CrawlState is the progress of every group crawl, kept on the harddisk so
+// that a crawl can be resumed.
+type CrawlState struct {
+	mu     sync.Mutex
+	Groups map[string]crawl.Checkpoint `json:"groups"`
+}
+
+// LoadCrawlState loads the crawl state from the harddisk. A missing file is
+// an empty state.
+func LoadCrawlState() (*CrawlState, error) {
+	s := &CrawlState{Groups: map[string]crawl.Checkpoint{}}
+
+	fileContents, err := ioutil.ReadFile(crawlStateFileDir)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(fileContents, s)
+	if err != nil {
+		return nil, err
+	}
+	if s.Groups == nil {
+		s.Groups = map[string]crawl.Checkpoint{}
+	}
+	return s, nil
+}
+
+// Checkpoint gets the saved progress of a group.
+func (s *CrawlState) Checkpoint(groupID string) (crawl.Checkpoint, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cp, ok := s.Groups[groupID]
+	return cp, ok
+}
+
+// SaveCheckpoint records the progress of a group and writes the state to the
+// harddisk.
+func (s *CrawlState) SaveCheckpoint(cp crawl.Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Groups[cp.GroupID] = cp
+	jsonMarshalled, err := json.MarshalIndent(s, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash never leaves a torn state.
+	err = ioutil.WriteFile(crawlStateFileDir+".tmp", jsonMarshalled, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(crawlStateFileDir+".tmp", crawlStateFileDir)
+}
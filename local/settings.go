@@ -5,22 +5,57 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+
+	"patrickwthomas.net/groupme-graph/groupme"
 )
 
 // Settings holds all of the relevant settings from the application.
 type Settings struct {
-	GroupMeAPI  string `json:"group_me_api"`
-	AccessToken string `json:"access_token"`
+	GroupMeAPI  string              `json:"group_me_api"`
+	AccessToken string              `json:"access_token"`
+	Groups      groupme.GroupFilter `json:"groups"`
+	// MediaDir is where downloaded images and attachments are kept.
+	MediaDir string `json:"media_dir"`
+	// MediaLayout is the path template of archived attachments inside MediaDir.
+	MediaLayout string `json:"media_layout"`
+	// EmojiPacks is a JSON file naming the emoji of GroupMe's emoji packs.
+	EmojiPacks string `json:"emoji_packs,omitempty"`
+	// TimeZone is the IANA time zone statistics are computed in, e.g.
+	// America/Chicago. The system time zone is used when it is empty.
+	TimeZone string `json:"time_zone,omitempty"`
+	// Scorers are the names of the scorers messages are rated with as they
+	// are saved, e.g. sentiment.
+	Scorers []string `json:"scorers,omitempty"`
+	// Stopwords are words left out of keywords, on top of common English
+	// words.
+	Stopwords []string `json:"stopwords,omitempty"`
 }
 
 const settingsFileDir = "./settings.json"
 const groupMeAPIUninit = "https://api.groupme.com/v3"
 const accessTokenUninit = "your API token here (get one here: https://dev.groupme.com/)"
+const mediaDirUninit = "./media"
+const mediaLayoutUninit = "{group}/{year}/{month}/{hash}{ext}"
 
 // LoadSettings loads configuration for the application from the harddisk.
 func LoadSettings() (*Settings, error) {
+	return LoadSettingsWithToken("")
+}
+
+// LoadSettingsWithToken loads configuration for the application from the
+// harddisk, using accessToken instead of the one in the file when it is set.
+// With a token, the settings file is optional and does not need a token of
+// its own.
+func LoadSettingsWithToken(accessToken string) (*Settings, error) {
 	fileContents, err := ioutil.ReadFile(settingsFileDir)
-	if os.IsNotExist(err) {
+	if os.IsNotExist(err) && accessToken != "" {
+		return &Settings{
+			GroupMeAPI:  groupMeAPIUninit,
+			AccessToken: accessToken,
+			MediaDir:    mediaDirUninit,
+			MediaLayout: mediaLayoutUninit,
+		}, nil
+	} else if os.IsNotExist(err) {
 		err := initSettings()
 		if err != nil {
 			return nil, err
@@ -32,8 +67,16 @@ func LoadSettings() (*Settings, error) {
 
 	s := new(Settings)
 	err = json.Unmarshal(fileContents, s)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", settingsFileDir, err)
+	}
 
-	if s.AccessToken == accessTokenUninit {
+	if accessToken != "" {
+		s.AccessToken = accessToken
+		if s.GroupMeAPI == "" {
+			s.GroupMeAPI = groupMeAPIUninit
+		}
+	} else if s.AccessToken == accessTokenUninit {
 		return nil, fmt.Errorf("access token needs to be configured at %s", settingsFileDir)
 	} else if s.AccessToken == "" && s.GroupMeAPI == "" {
 		return nil, fmt.Errorf("settings file is empty")
@@ -42,6 +85,24 @@ func LoadSettings() (*Settings, error) {
 	return s, nil
 }
 
+// ReadSettings reads the settings file as it is, for commands that need
+// neither GroupMe nor its access token. It returns nil when there is no
+// settings file, and never creates one.
+func ReadSettings() (*Settings, error) {
+	fileContents, err := ioutil.ReadFile(settingsFileDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	s := new(Settings)
+	err = json.Unmarshal(fileContents, s)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", settingsFileDir, err)
+	}
+	return s, nil
+}
+
 // SaveSettings Save settings to a file.
 func SaveSettings(s *Settings) error {
 	jsonMarshalled, _ := json.MarshalIndent(s, "", "\t")
@@ -63,6 +124,8 @@ func initSettings() error {
 	s := Settings{
 		GroupMeAPI:  groupMeAPIUninit,
 		AccessToken: accessTokenUninit,
+		MediaDir:    mediaDirUninit,
+		MediaLayout: mediaLayoutUninit,
 	}
 
 	err := SaveSettings(&s)
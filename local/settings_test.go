@@ -1,8 +1,10 @@
 package local
 
 import (
+	"io/ioutil"
 	"log"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -120,3 +122,60 @@ func TestSaveSettings(t *testing.T) {
 		log.Panic(err)
 	}
 }
+
+func TestLoadSettingsWithTokenNoFile(t *testing.T) {
+	setupTestDir()
+
+	s, err := LoadSettingsWithToken("env token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.AccessToken != "env token" || s.GroupMeAPI != groupMeAPIUninit {
+		t.Errorf("got %+v", s)
+	}
+	_, err = os.Stat(settingsFileDir)
+	if !os.IsNotExist(err) {
+		t.Error("settings file was created")
+	}
+}
+
+func TestLoadSettingsWithTokenUninit(t *testing.T) {
+	setupTestDir()
+
+	err := initSettings()
+	if err != nil {
+		log.Panic(err)
+	}
+	s, err := LoadSettingsWithToken("env token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.AccessToken != "env token" || s.MediaDir != mediaDirUninit {
+		t.Errorf("got %+v", s)
+	}
+}
+
+func TestLoadSettingsMalformed(t *testing.T) {
+	setupTestDir()
+
+	err := ioutil.WriteFile(settingsFileDir, []byte(`{"access_token": `), 0644)
+	if err != nil {
+		log.Panic(err)
+	}
+	_, err = LoadSettingsWithToken("")
+	if err == nil || !strings.Contains(err.Error(), settingsFileDir) {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestReadSettingsNoFile(t *testing.T) {
+	setupTestDir()
+
+	settings, err := ReadSettings()
+	if err != nil || settings != nil {
+		t.Errorf("got %v, %v", settings, err)
+	}
+	if _, err = os.Stat(settingsFileDir); !os.IsNotExist(err) {
+		t.Error("settings file was created")
+	}
+}
@@ -4,36 +4,130 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sort"
 
+	"patrickwthomas.net/groupme-graph/crawl"
 	"patrickwthomas.net/groupme-graph/database"
 	"patrickwthomas.net/groupme-graph/groupme"
+	"patrickwthomas.net/groupme-graph/local"
+	"patrickwthomas.net/groupme-graph/score"
 )
 
-const groupIDTowel = "62858190"
+// command is a subcommand of the application.
+type command struct {
+	usage string
+	run   func(args []string)
+}
+
+var commands = map[string]command{
+	"archive-media":   {"download the images, videos and files attached to messages", archiveMediaCommand},
+	"crawl":           {"fetch the history of the selected groups into Neo4j", crawlCommand},
+	"download-images": {"download avatars and group images into the media directory", downloadImagesCommand},
+	"import":          {"load GroupMe data export archives into Neo4j", importCommand},
+	"report":          {"generate reports about groups, like a year in review", reportCommand},
+	"search":          {"search messages and groups by their text", searchCommand},
+	"stats":           {"compute statistics about groups and their members", statsCommand},
+}
 
 func main() {
-	database.Init()
-	driver, err := database.NewNeo4j("bolt://localhost:7687", "", "", false)
+	name := "crawl"
+	args := []string{}
+	if len(os.Args) > 1 {
+		name = os.Args[1]
+		args = os.Args[2:]
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		printUsage()
+		os.Exit(2)
+	}
+	cmd.run(args)
+}
+
+func printUsage() {
+	names := []string{}
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\nCommands:\n", os.Args[0])
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", name, commands[name].usage)
+	}
+}
+
+// loadSettings loads the settings file, stopping the application if it is not
+// usable. GROUPME_ACCESS_TOKEN overrides the access token from the settings
+// file, which is then not needed at all.
+func loadSettings() *local.Settings {
+	settings, err := local.LoadSettingsWithToken(os.Getenv("GROUPME_ACCESS_TOKEN"))
 	if err != nil {
 		log.Panic(err)
 	}
+	return settings
+}
 
-	g := groupme.NewGroupMe(os.Getenv("GROUPME_ACCESS_TOKEN"))
-	groupIndex := g.GroupsIndex(1, 100, false)
+// readSettings loads the settings of commands that never call GroupMe, which
+// work without a settings file or an access token.
+func readSettings() *local.Settings {
+	settings, err := local.ReadSettings()
+	if err != nil {
+		log.Panic(err)
+	}
+	if settings == nil {
+		return new(local.Settings)
+	}
+	return settings
+}
 
-	fmt.Printf("Found %d groups.\n", len(groupIndex))
+// newGroupMe creates a GroupMe client from the settings.
+func newGroupMe(settings *local.Settings) *groupme.GroupMe {
+	g := groupme.NewGroupMe(settings.AccessToken)
+	if settings.GroupMeAPI != "" {
+		g.API = settings.GroupMeAPI
+	}
+	return g
+}
 
-	for _, group := range groupIndex {
-		group.SaveToNeo4j(driver)
+// mediaDir is the directory downloaded media is kept in.
+func mediaDir(settings *local.Settings) string {
+	if settings.MediaDir == "" {
+		return "./media"
 	}
+	return settings.MediaDir
+}
 
-	groupTowel := g.GroupsShow(groupIDTowel)
+// emojiCatalog loads the emoji packs named in the settings. Without any, emoji
+// are shown by their pack and index.
+func emojiCatalog(settings *local.Settings) groupme.EmojiCatalog {
+	if settings.EmojiPacks == "" {
+		return nil
+	}
+	packs, err := groupme.LoadEmojiPacks(settings.EmojiPacks)
+	if err != nil {
+		log.Panic(err)
+	}
+	return packs
+}
 
-	groupTowelMessages := g.MessagesIndex(groupTowel.ID, "", "", "", 50)
-	for i := 0; i < len(groupTowelMessages); i++ {
-		m := groupTowelMessages[i]
-		m.SaveToNeo4j(driver)
+// newStore creates the store crawled and imported data is written to, rating
+// messages with the scorers from the settings.
+func newStore(settings *local.Settings, driver *database.Neo4j) *crawl.Neo4jStore {
+	scorers, err := score.New(settings.Scorers...)
+	if err != nil {
+		log.Panic(err)
 	}
+	return &crawl.Neo4jStore{Driver: driver, Scorers: scorers}
+}
 
-	groupme.Connect(driver)
+// connectNeo4j prepares the database and connects to it.
+func connectNeo4j() *database.Neo4j {
+	database.Init()
+	driver, err := database.NewNeo4j("bolt://localhost:7687", "", "", false)
+	if err != nil {
+		log.Panic(err)
+	}
+	return driver
 }
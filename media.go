@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"patrickwthomas.net/groupme-graph/database"
+	"patrickwthomas.net/groupme-graph/media"
+)
+
+func archiveMediaCommand(args []string) {
+	flags := flag.NewFlagSet("archive-media", flag.ExitOnError)
+	workers := flags.Int("workers", 8, "number of files downloaded at the same time")
+	layout := flags.String("layout", "", "path template of archived files, overriding media_layout")
+	flags.Parse(args)
+	if *workers <= 0 {
+		log.Panicf("-workers must be at least 1, got %d", *workers)
+	}
+
+	settings := loadSettings()
+	store := media.NewStore(mediaDir(settings))
+	if *layout != "" {
+		store.Layout = media.Layout(*layout)
+	} else if settings.MediaLayout != "" {
+		store.Layout = media.Layout(settings.MediaLayout)
+	}
+	err := store.Layout.Validate()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	driver := connectNeo4j()
+	rememberKnownFiles(driver, store)
+	downloader := media.NewDownloader(store)
+	downloader.Token = newGroupMe(settings).APIKey
+
+	items, err := media.PendingAttachments(driver)
+	if err != nil {
+		log.Panic(err)
+	}
+	fmt.Printf("Archiving %d files.\n", len(items))
+
+	archived, failed := 0, 0
+	err = downloader.Archive(context.Background(), items, *workers, func(result media.Result) error {
+		if result.Err != nil {
+			log.Printf("Skipping %s: %v", result.Item.URL, result.Err)
+			failed++
+			return nil
+		}
+		archived++
+		return media.RecordAttachments(driver, result.Item.AttachmentIDs, result.Hash, result.Path)
+	})
+	if err != nil {
+		log.Panic(err)
+	}
+	fmt.Printf("Archived %d files, %d failed.\n", archived, failed)
+}
+
+// rememberKnownFiles tells the store about the files already recorded in the
+// database, so they are not stored twice.
+func rememberKnownFiles(driver *database.Neo4j, store *media.Store) {
+	known, err := media.KnownFiles(driver)
+	if err != nil {
+		log.Panic(err)
+	}
+	for hash, path := range known {
+		store.Remember(hash, path)
+	}
+}
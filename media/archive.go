@@ -0,0 +1,81 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Item is a file to archive. Several attachments can share the same file.
+type Item struct {
+	URL           string
+	Info          Info
+	AttachmentIDs []string
+}
+
+// Result is the outcome of archiving an item.
+type Result struct {
+	Item Item
+	Hash string
+	Path string
+	Err  error
+}
+
+// ErrNoWorkers is returned by Archive when it has no workers to download
+// with.
+var ErrNoWorkers = errors.New("media: at least one worker is needed")
+
+// Archive downloads the items with a pool of workers. Every result, failed or
+// not, is handed to record from a single goroutine, so record does not need
+// to be safe for concurrent use. Archive stops at the first error from record.
+func (d *Downloader) Archive(ctx context.Context, items []Item, workers int, record func(Result) error) error {
+	if workers < 1 {
+		return ErrNoWorkers
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan Item)
+	results := make(chan Result, workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range jobs {
+				hash, path, err := d.Fetch(ctx, item.URL, item.Info)
+				results <- Result{Item: item, Hash: hash, Path: path, Err: err}
+			}
+		}()
+	}
+
+	go func() {
+	feed:
+		for _, item := range items {
+			select {
+			case jobs <- item:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	var recordErr error
+	for result := range results {
+		if recordErr != nil {
+			continue
+		}
+		recordErr = record(result)
+		if recordErr != nil {
+			cancel()
+		}
+	}
+	if recordErr != nil {
+		return recordErr
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,85 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// fileServiceHost serves the files attached to messages.
+const fileServiceHost = "file.groupme.com"
+
+// FileURL is where the file attached to a message in a group can be
+// downloaded from.
+func FileURL(groupID, fileID string) string {
+	return fmt.Sprintf("https://%s/v1/%s/files/%s", fileServiceHost, groupID, fileID)
+}
+
+// extensions are the preferred file extensions for common media types.
+var extensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"video/mp4":       ".mp4",
+	"video/quicktime": ".mov",
+	"application/pdf": ".pdf",
+}
+
+// Downloader fetches files, such as images from GroupMe's image CDN, into a
+// store.
+type Downloader struct {
+	Client *http.Client
+	Store  *Store
+	// Token is sent to the services that need it, like GroupMe's file service.
+	Token string
+}
+
+// NewDownloader creates a downloader writing into a store.
+func NewDownloader(store *Store) *Downloader {
+	d := new(Downloader)
+	d.Client = http.DefaultClient
+	d.Store = store
+	return d
+}
+
+// Fetch downloads a URL into the store, returning the hex SHA-256 of the file
+// and its path. The extension of info is filled in from the response.
+func (d *Downloader) Fetch(ctx context.Context, url string, info Info) (string, string, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", "", err
+	}
+	if d.Token != "" && request.URL.Host == fileServiceHost {
+		request.Header.Set("X-Access-Token", d.Token)
+	}
+	response, err := d.Client.Do(request)
+	if err != nil {
+		return "", "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode/100 != 2 {
+		return "", "", fmt.Errorf("fetching %s: %s", url, response.Status)
+	}
+	info.Ext = extension(response.Header.Get("Content-Type"))
+	return d.Store.Put(response.Body, info)
+}
+
+// extension picks a file extension for a content type.
+func extension(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	if ext, ok := extensions[mediaType]; ok {
+		return ext
+	}
+	exts, err := mime.ExtensionsByType(mediaType)
+	if err != nil || len(exts) == 0 {
+		return ""
+	}
+	return strings.ToLower(exts[0])
+}
@@ -0,0 +1,76 @@
+package media
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultLayout keeps files under the first two characters of their hash.
+const DefaultLayout = "{hash2}/{hash}{ext}"
+
+// Info describes a file being stored, for use by the layout.
+type Info struct {
+	// Ext is the file extension, including the dot.
+	Ext       string
+	Type      string
+	GroupID   string
+	MessageID string
+	CreatedAt int
+}
+
+// Layout places files inside the store. It is a path template where these
+// placeholders are replaced:
+//
+//	{hash}     the hex SHA-256 of the file
+//	{hash2}    the first two characters of the hash
+//	{ext}      the file extension, including the dot
+//	{type}     the attachment type, e.g. image or video
+//	{group}    the ID of the group the file was sent in
+//	{message}  the ID of the message the file was sent with
+//	{year}, {month}, {day}  when the message was sent, in UTC
+//
+// Unknown values are replaced with an underscore.
+type Layout string
+
+// Validate checks that the layout keeps different files apart.
+func (l Layout) Validate() error {
+	if !strings.Contains(string(l), "{hash}") {
+		return fmt.Errorf("media layout %q must contain {hash}", l)
+	}
+	return nil
+}
+
+// Path is where a file goes, relative to the root of the store.
+func (l Layout) Path(hash string, info Info) string {
+	if l == "" {
+		l = DefaultLayout
+	}
+
+	year, month, day := "_", "_", "_"
+	if info.CreatedAt > 0 {
+		t := time.Unix(int64(info.CreatedAt), 0).UTC()
+		year, month, day = t.Format("2006"), t.Format("01"), t.Format("02")
+	}
+	replacer := strings.NewReplacer(
+		"{hash}", hash,
+		"{hash2}", hash[:2],
+		"{ext}", info.Ext,
+		"{type}", orUnknown(info.Type),
+		"{group}", orUnknown(info.GroupID),
+		"{message}", orUnknown(info.MessageID),
+		"{year}", year,
+		"{month}", month,
+		"{day}", day,
+	)
+	return filepath.FromSlash(replacer.Replace(string(l)))
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "_"
+	}
+	// Keep values from escaping their directory.
+	return strings.NewReplacer("/", "_", "\\", "_", "..", "_").Replace(s)
+}
@@ -0,0 +1,162 @@
+package media
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeCDN serves images like i.groupme.com does. Two of the URLs point at the
+// same picture.
+func fakeCDN() *httptest.Server {
+	images := map[string]string{
+		"/750x750.jpeg.aaa": "first picture",
+		"/750x750.jpeg.bbb": "first picture",
+		"/500x500.png.ccc":  "second picture",
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contents, ok := images[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if filepath.Ext(r.URL.Path) == ".ccc" {
+			w.Header().Set("Content-Type", "image/png")
+		} else {
+			w.Header().Set("Content-Type", "image/jpeg")
+		}
+		w.Write([]byte(contents))
+	}))
+}
+
+func newTestDownloader(t *testing.T) (*Downloader, func()) {
+	dir, err := ioutil.TempDir("", "groupme-media")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewDownloader(NewStore(dir)), func() { os.RemoveAll(dir) }
+}
+
+func TestFetchDeduplicates(t *testing.T) {
+	cdn := fakeCDN()
+	defer cdn.Close()
+	d, cleanup := newTestDownloader(t)
+	defer cleanup()
+
+	hashA, pathA, err := d.Fetch(context.Background(), cdn.URL+"/750x750.jpeg.aaa", Info{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashB, pathB, err := d.Fetch(context.Background(), cdn.URL+"/750x750.jpeg.bbb", Info{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashC, pathC, err := d.Fetch(context.Background(), cdn.URL+"/500x500.png.ccc", Info{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hashA != hashB || pathA != pathB {
+		t.Errorf("same picture stored twice: %s and %s", pathA, pathB)
+	}
+	if hashA == hashC {
+		t.Error("different pictures have the same hash")
+	}
+	if filepath.Ext(pathA) != ".jpg" || filepath.Ext(pathC) != ".png" {
+		t.Errorf("bad extensions: %s and %s", pathA, pathC)
+	}
+
+	contents, err := ioutil.ReadFile(pathC)
+	if err != nil || string(contents) != "second picture" {
+		t.Errorf("bad contents %q (%v)", contents, err)
+	}
+
+	// Only the two distinct pictures are left, without temporary files.
+	files := 0
+	filepath.Walk(d.Store.Root, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			files++
+		}
+		return nil
+	})
+	if files != 2 {
+		t.Errorf("found %d files, want 2", files)
+	}
+}
+
+func TestFetchNotFound(t *testing.T) {
+	cdn := fakeCDN()
+	defer cdn.Close()
+	d, cleanup := newTestDownloader(t)
+	defer cleanup()
+
+	_, _, err := d.Fetch(context.Background(), cdn.URL+"/missing", Info{})
+	if err == nil {
+		t.Fail()
+	}
+}
+
+func TestArchive(t *testing.T) {
+	cdn := fakeCDN()
+	defer cdn.Close()
+	d, cleanup := newTestDownloader(t)
+	defer cleanup()
+	d.Store.Layout = "{group}/{year}/{month}/{hash}{ext}"
+
+	// 2020-03-01 in UTC.
+	info := Info{GroupID: "62858190", CreatedAt: 1583020800}
+	items := []Item{
+		{URL: cdn.URL + "/750x750.jpeg.aaa", Info: info, AttachmentIDs: []string{"1:0"}},
+		{URL: cdn.URL + "/750x750.jpeg.bbb", Info: info, AttachmentIDs: []string{"2:0", "3:0"}},
+		{URL: cdn.URL + "/500x500.png.ccc", Info: info, AttachmentIDs: []string{"4:0"}},
+		{URL: cdn.URL + "/missing", Info: info, AttachmentIDs: []string{"5:0"}},
+	}
+
+	results := map[string]Result{}
+	err := d.Archive(context.Background(), items, 3, func(result Result) error {
+		results[result.Item.URL] = result
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 4 {
+		t.Fatalf("got %d results, want 4", len(results))
+	}
+	a, b := results[items[0].URL], results[items[1].URL]
+	if a.Err != nil || b.Err != nil || a.Path != b.Path {
+		t.Errorf("duplicate pictures were not deduplicated: %+v %+v", a, b)
+	}
+	want := filepath.Join(d.Store.Root, "62858190", "2020", "03", a.Hash+".jpg")
+	if a.Path != want {
+		t.Errorf("got path %s, want %s", a.Path, want)
+	}
+	if results[items[3].URL].Err == nil {
+		t.Error("missing file did not fail")
+	}
+}
+
+func TestArchiveNoWorkers(t *testing.T) {
+	d, cleanup := newTestDownloader(t)
+	defer cleanup()
+
+	items := []Item{{URL: "https://i.groupme.com/750x750.jpeg.aaa"}}
+	err := d.Archive(context.Background(), items, 0, func(Result) error { return nil })
+	if err != ErrNoWorkers {
+		t.Errorf("got %v, want ErrNoWorkers", err)
+	}
+}
+
+func TestLayoutValidate(t *testing.T) {
+	if Layout("{group}/{ext}").Validate() == nil {
+		t.Error("layout without the hash was accepted")
+	}
+	if Layout(DefaultLayout).Validate() != nil {
+		t.Error("default layout was rejected")
+	}
+}
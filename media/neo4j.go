@@ -0,0 +1,145 @@
+package media
+
+import (
+	"github.com/neo4j/neo4j-go-driver/neo4j"
+	"patrickwthomas.net/groupme-graph/database"
+)
+
+// PendingImages lists the avatar and group image URLs that have not been
+// downloaded yet.
+func PendingImages(driver *database.Neo4j) ([]string, error) {
+	session, err := driver.NewReadSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	result, err := session.Run(`MATCH (n) WHERE (n:Avatar OR n:GroupImage) AND n.sha256 IS NULL
+	RETURN DISTINCT n.url`, map[string]interface{}{})
+	if err != nil {
+		return nil, err
+	}
+
+	urls := []string{}
+	for result.Next() {
+		if url, ok := result.Record().GetByIndex(0).(string); ok {
+			urls = append(urls, url)
+		}
+	}
+	return urls, result.Err()
+}
+
+// RecordImage stores where an avatar or group image was downloaded to.
+func RecordImage(driver *database.Neo4j, url, hash, path string) error {
+	session, err := driver.NewWriteSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	_, err = session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(`MATCH (n) WHERE (n:Avatar OR n:GroupImage) AND n.url = $url
+		SET n.sha256 = $hash, n.path = $path`, map[string]interface{}{"url": url, "hash": hash, "path": path})
+		if err != nil {
+			return nil, err
+		}
+		return result.Consume()
+	})
+	return err
+}
+
+// archivedTypes are the attachment types that point at a file.
+var archivedTypes = []string{"image", "linked_image", "video", "file"}
+
+// PendingAttachments lists the files of attachments that have not been
+// archived yet. Attachments sharing a URL become a single item.
+func PendingAttachments(driver *database.Neo4j) ([]Item, error) {
+	session, err := driver.NewReadSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	result, err := session.Run(`MATCH (m:Message)-[:HAS_ATTACHMENT]->(a:Attachment)
+	WHERE a.Type IN $types AND a.sha256 IS NULL
+	RETURN a.ID, a.Type, a.URL, a.FileID, m.GroupID, m.ID, m.CreatedAt
+	ORDER BY m.CreatedAt`, map[string]interface{}{"types": archivedTypes})
+	if err != nil {
+		return nil, err
+	}
+
+	items := []Item{}
+	byURL := map[string]int{}
+	for result.Next() {
+		values := result.Record().Values()
+		id, _ := values[0].(string)
+		attachmentType, _ := values[1].(string)
+		url, _ := values[2].(string)
+		fileID, _ := values[3].(string)
+		groupID, _ := values[4].(string)
+		messageID, _ := values[5].(string)
+		createdAt, _ := values[6].(int64)
+
+		if attachmentType == "file" && fileID != "" {
+			url = FileURL(groupID, fileID)
+		}
+		if url == "" {
+			continue
+		}
+
+		if i, ok := byURL[url]; ok {
+			items[i].AttachmentIDs = append(items[i].AttachmentIDs, id)
+			continue
+		}
+		byURL[url] = len(items)
+		items = append(items, Item{
+			URL:           url,
+			Info:          Info{Type: attachmentType, GroupID: groupID, MessageID: messageID, CreatedAt: int(createdAt)},
+			AttachmentIDs: []string{id},
+		})
+	}
+	return items, result.Err()
+}
+
+// RecordAttachments stores where the file of some attachments was archived.
+func RecordAttachments(driver *database.Neo4j, ids []string, hash, path string) error {
+	session, err := driver.NewWriteSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	_, err = session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(`MATCH (a:Attachment) WHERE a.ID IN $ids
+		SET a.sha256 = $hash, a.path = $path`, map[string]interface{}{"ids": ids, "hash": hash, "path": path})
+		if err != nil {
+			return nil, err
+		}
+		return result.Consume()
+	})
+	return err
+}
+
+// KnownFiles maps the hashes of every file recorded in the database to their
+// paths, so a store does not keep a second copy of them.
+func KnownFiles(driver *database.Neo4j) (map[string]string, error) {
+	session, err := driver.NewReadSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	result, err := session.Run(`MATCH (n) WHERE (n:Attachment OR n:Avatar OR n:GroupImage) AND n.sha256 IS NOT NULL
+	RETURN DISTINCT n.sha256, n.path`, map[string]interface{}{})
+	if err != nil {
+		return nil, err
+	}
+
+	known := map[string]string{}
+	for result.Next() {
+		hash, _ := result.Record().GetByIndex(0).(string)
+		path, _ := result.Record().GetByIndex(1).(string)
+		known[hash] = path
+	}
+	return known, result.Err()
+}
@@ -0,0 +1,91 @@
+package media
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store keeps files on the harddisk, identified by the SHA-256 of their
+// contents, so the same file is only ever stored once. It is safe for
+// concurrent use.
+type Store struct {
+	// Root is the directory the files are kept in.
+	Root   string
+	Layout Layout
+
+	mu sync.Mutex
+	// known maps the hashes of stored files to their paths.
+	known map[string]string
+}
+
+// NewStore creates a store rooted at a directory.
+func NewStore(root string) *Store {
+	s := new(Store)
+	s.Root = root
+	s.Layout = DefaultLayout
+	s.known = map[string]string{}
+	return s
+}
+
+// Remember records that a file with the given hash is already stored, so it
+// is not stored again under a different path.
+func (s *Store) Remember(hash, path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.known[hash] = path
+}
+
+// Put copies the contents of r into the store, returning their hex SHA-256
+// and the path they are kept at. Contents that are already stored are not
+// written again.
+func (s *Store) Put(r io.Reader, info Info) (string, string, error) {
+	err := os.MkdirAll(s.Root, os.ModePerm)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Write to a temporary file first, since the name depends on the contents.
+	tmp, err := ioutil.TempFile(s.Root, ".download-")
+	if err != nil {
+		return "", "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, hash), r)
+	closeErr := tmp.Close()
+	if err != nil {
+		return "", "", err
+	} else if closeErr != nil {
+		return "", "", closeErr
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if path, ok := s.known[sum]; ok {
+		return sum, path, nil
+	}
+	path := filepath.Join(s.Root, s.Layout.Path(sum, info))
+	if _, err := os.Stat(path); err == nil {
+		s.known[sum] = path
+		return sum, path, nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return "", "", err
+	}
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		return "", "", err
+	}
+	s.known[sum] = path
+	return sum, path, nil
+}
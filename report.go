@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"patrickwthomas.net/groupme-graph/analytics"
+	"patrickwthomas.net/groupme-graph/report"
+)
+
+// reportCommands are the subcommands of the report command.
+var reportCommands = map[string]command{
+	"wrapped": {"write a year in review of each group as an HTML page", wrappedCommand},
+}
+
+func reportCommand(args []string) {
+	if len(args) == 0 {
+		printReportUsage()
+		os.Exit(2)
+	}
+	cmd, ok := reportCommands[args[0]]
+	if !ok {
+		printReportUsage()
+		os.Exit(2)
+	}
+	cmd.run(args[1:])
+}
+
+func printReportUsage() {
+	names := []string{}
+	for name := range reportCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(os.Stderr, "Usage: %s report <report> [flags]\n\nReports:\n", os.Args[0])
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", name, reportCommands[name].usage)
+	}
+}
+
+func wrappedCommand(args []string) {
+	flags := flag.NewFlagSet("report wrapped", flag.ExitOnError)
+	year := flags.Int("year", time.Now().Year(), "year to review")
+	groupID := flags.String("group", "", "only review this group ID")
+	top := flags.Int("top", 10, "number of members, messages, emoji, days, streaks and mentions shown, 0 for all")
+	tz := flags.String("tz", "", "time zone of the year and its days, defaults to time_zone from the settings")
+	out := flags.String("out", ".", "directory the reports are written into")
+	flags.Parse(args)
+
+	settings := readSettings()
+	loc := statsLocation(settings, *tz)
+	catalog := emojiCatalog(settings)
+	driver := connectNeo4j()
+	err := os.MkdirAll(*out, 0755)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	window := report.YearWindow(*year, loc)
+	for _, group := range statsGroups(driver, *groupID) {
+		messages, err := report.LoadMessages(driver, group.ID, window, catalog)
+		if err != nil {
+			log.Panic(err)
+		}
+		if len(messages) == 0 {
+			continue
+		}
+		interactions, err := analytics.LoadInteractions(driver, group.ID)
+		if err != nil {
+			log.Panic(err)
+		}
+		names, err := analytics.LoadMemberNames(driver, group.ID)
+		if err != nil {
+			log.Panic(err)
+		}
+
+		wrapped := report.NewWrapped(group.Name, *year, messages, interactions, names, loc, *top)
+		name := filepath.Join(*out, fmt.Sprintf("wrapped-%d-%s.html", *year, group.ID))
+		file, err := os.Create(name)
+		if err != nil {
+			log.Panic(err)
+		}
+		err = report.WriteHTML(file, wrapped)
+		if err != nil {
+			log.Panic(err)
+		}
+		err = file.Close()
+		if err != nil {
+			log.Panic(err)
+		}
+		fmt.Printf("Wrote %s, %d messages.\n", name, wrapped.Messages)
+	}
+}
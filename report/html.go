@@ -0,0 +1,81 @@
+package report
+
+import (
+	"html/template"
+	"io"
+	"time"
+)
+
+// WriteHTML writes a year in review as a single HTML page, with its styles
+// inline so it can be shared as one file.
+func WriteHTML(w io.Writer, wrapped Wrapped) error {
+	return wrappedTemplate.Execute(w, wrapped)
+}
+
+var wrappedTemplate = template.Must(template.New("wrapped").Funcs(template.FuncMap{
+	"date": func(t time.Time) string { return t.Format("Mon Jan 2") },
+	"time": func(t time.Time) string { return t.Format("Jan 2, 3:04 PM") },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Group}} {{.Year}} Wrapped</title>
+<style>
+body { margin: 0; background: #101828; color: #f2f4f7; font-family: -apple-system, "Segoe UI", Roboto, sans-serif; }
+main { max-width: 720px; margin: 0 auto; padding: 32px 16px; }
+h1 { font-size: 40px; margin: 0 0 8px; }
+h2 { font-size: 20px; margin: 0 0 12px; color: #7cd4fd; }
+section { background: #1d2939; border-radius: 12px; padding: 20px; margin: 16px 0; }
+ol { margin: 0; padding-left: 24px; }
+li { margin: 6px 0; }
+.count { float: right; color: #98a2b3; }
+.summary { color: #98a2b3; font-size: 18px; }
+blockquote { margin: 8px 0; padding: 8px 12px; border-left: 3px solid #7cd4fd; white-space: pre-wrap; }
+.meta { color: #98a2b3; font-size: 14px; }
+.emoji { font-size: 24px; }
+</style>
+</head>
+<body>
+<main>
+<h1>{{.Group}} {{.Year}} Wrapped</h1>
+<p class="summary">{{.Messages}} messages from {{.Members}} members.</p>
+{{with .First}}<section>
+<h2>First message of the year</h2>
+<blockquote>{{.Text}}</blockquote>
+<div class="meta">{{.Name}}, {{time .At}}</div>
+</section>{{end}}
+{{with .Active}}<section>
+<h2>Most active members</h2>
+<ol>{{range .}}<li>{{.Name}}<span class="count">{{.Count}} messages</span></li>{{end}}</ol>
+</section>{{end}}
+{{with .Liked}}<section>
+<h2>Most liked messages</h2>
+{{range .}}<blockquote>{{.Text}}</blockquote>
+<div class="meta">{{.Name}}, {{time .At}} · liked by {{.Likes}}</div>
+{{end}}</section>{{end}}
+{{with .Emoji}}<section>
+<h2>Top emoji</h2>
+<ol>{{range .}}<li><span class="emoji">{{.Name}}</span><span class="count">{{.Count}} times</span></li>{{end}}</ol>
+</section>{{end}}
+{{with .Busiest}}<section>
+<h2>Busiest days</h2>
+<ol>{{range .}}<li>{{date .Date}}<span class="count">{{.Messages}} messages</span></li>{{end}}</ol>
+</section>{{end}}
+{{with .Streaks}}<section>
+<h2>Longest streaks</h2>
+<ol>{{range .}}<li>{{.Name}}<span class="count">{{.Days}} days, {{date .Start}} to {{date .End}}</span></li>{{end}}</ol>
+</section>{{end}}
+{{with .Mentions}}<section>
+<h2>Top mentions</h2>
+<ol>{{range .}}<li>{{.Name}}<span class="count">{{.Count}} times</span></li>{{end}}</ol>
+</section>{{end}}
+{{with .Last}}<section>
+<h2>Last message of the year</h2>
+<blockquote>{{.Text}}</blockquote>
+<div class="meta">{{.Name}}, {{time .At}}</div>
+</section>{{end}}
+</main>
+</body>
+</html>
+`))
@@ -0,0 +1,58 @@
+package report
+
+import (
+	"patrickwthomas.net/groupme-graph/analytics"
+	"patrickwthomas.net/groupme-graph/database"
+	"patrickwthomas.net/groupme-graph/groupme"
+)
+
+// LoadMessages loads the messages members sent to a group during a window,
+// oldest first, with the emoji they were reacted to with. GroupMe emoji in
+// their text are written by name from the catalog.
+func LoadMessages(driver *database.Neo4j, groupID string, window analytics.Window, catalog groupme.EmojiCatalog) ([]Message, error) {
+	session, err := driver.NewReadSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	result, err := session.Run(`MATCH (m:Message{GroupID: $group})
+	WHERE coalesce(m.System, false) = false AND m.UserID <> "system"
+		AND m.CreatedAt >= $start AND m.CreatedAt < $end
+	OPTIONAL MATCH (member:Member)-[r:REACTED]->(m)
+	WITH m, collect([member.UserID, r.emoji]) AS reactions
+	RETURN m.ID, m.UserID, m.CreatedAt, coalesce(m.Text, ""), reactions,
+		[(m)-[:HAS_ATTACHMENT]->(a:Attachment{Type: "emoji"}) | [a.Placeholder, a.Charmap]]
+	ORDER BY m.CreatedAt, m.ID`,
+		map[string]interface{}{"group": groupID, "start": window.Start, "end": window.End})
+	if err != nil {
+		return nil, err
+	}
+	messages := []Message{}
+	for result.Next() {
+		values := result.Record().Values()
+		m := Message{}
+		m.ID, _ = values[0].(string)
+		m.UserID, _ = values[1].(string)
+		at, _ := values[2].(int64)
+		m.At = int(at)
+		m.Text, _ = values[3].(string)
+		reactions, _ := values[4].([]interface{})
+		for _, value := range reactions {
+			pair, _ := value.([]interface{})
+			if len(pair) != 2 {
+				continue
+			}
+			r := Reaction{}
+			r.UserID, _ = pair[0].(string)
+			r.Emoji, _ = pair[1].(string)
+			if r.Emoji != "" {
+				m.Reactions = append(m.Reactions, r)
+			}
+		}
+		rendered := groupme.Message{Text: m.Text, Attachments: groupme.EmojiAttachments(values[5])}
+		m.Text = rendered.RenderText(catalog)
+		messages = append(messages, m)
+	}
+	return messages, result.Err()
+}
@@ -0,0 +1,306 @@
+// Package report generates reports about groups from the graph.
+package report
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"patrickwthomas.net/groupme-graph/analytics"
+)
+
+// Message is a message sent during the year of a report.
+type Message struct {
+	ID     string
+	UserID string
+	At     int
+	Text   string
+	// Reactions are the emoji members reacted with, one per reaction.
+	Reactions []Reaction
+}
+
+// Reaction is an emoji a member reacted to a message with.
+type Reaction struct {
+	UserID string
+	Emoji  string
+}
+
+// Likes is the number of members who reacted to the message, however many
+// emoji each of them reacted with.
+func (m Message) Likes() int {
+	members := map[string]bool{}
+	for _, r := range m.Reactions {
+		members[r.UserID] = true
+	}
+	return len(members)
+}
+
+// Ranked is a member, emoji or pair of members and how often they came up.
+type Ranked struct {
+	Name  string
+	Count int
+}
+
+// Day is the number of messages sent on a day.
+type Day struct {
+	Date     time.Time
+	Messages int
+}
+
+// Streak is the longest run of consecutive days a member posted on.
+type Streak struct {
+	Name  string
+	Days  int
+	Start time.Time
+	End   time.Time
+}
+
+// Highlight is a message shown in a report, with its author's name.
+type Highlight struct {
+	Name string
+	At   time.Time
+	Message
+}
+
+// Wrapped is a group's year in review.
+type Wrapped struct {
+	Group    string
+	Year     int
+	Messages int
+	Members  int
+	// Active are the members who sent the most messages.
+	Active []Ranked
+	// Liked are the messages with the most reactions.
+	Liked []Highlight
+	// Emoji are the emoji most used in messages and reactions.
+	Emoji []Ranked
+	// Busiest are the days with the most messages.
+	Busiest []Day
+	// Streaks are the longest runs of days members posted on.
+	Streaks []Streak
+	// Mentions are the members who mentioned each other the most, as
+	// "who → whom".
+	Mentions []Ranked
+	First    *Highlight
+	Last     *Highlight
+}
+
+// YearWindow is the span of a year in a time zone.
+func YearWindow(year int, loc *time.Location) analytics.Window {
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	return analytics.Window{Start: int(start.Unix()), End: int(start.AddDate(1, 0, 0).Unix())}
+}
+
+// NewWrapped computes the year in review of a group from its messages and
+// mentions, keeping the top of every ranking, or all of it when top is 0.
+// Messages and mentions outside of the year are ignored; messages must be
+// oldest first.
+func NewWrapped(group string, year int, messages []Message, mentions []analytics.Interaction, names map[string]string, loc *time.Location, top int) Wrapped {
+	window := YearWindow(year, loc)
+	w := Wrapped{Group: group, Year: year}
+	name := func(userID string) string {
+		if n := names[userID]; n != "" {
+			return n
+		}
+		return userID
+	}
+	highlight := func(m Message) Highlight {
+		return Highlight{Name: name(m.UserID), At: time.Unix(int64(m.At), 0).In(loc), Message: m}
+	}
+
+	posts := map[string]int{}
+	emoji := map[string]int{}
+	days := map[time.Time]int{}
+	postedOn := map[string]map[time.Time]bool{}
+	inYear := []Message{}
+	for _, m := range messages {
+		if m.At < window.Start || m.At >= window.End {
+			continue
+		}
+		inYear = append(inYear, m)
+		posts[m.UserID]++
+		for _, e := range Emoji(m.Text) {
+			emoji[e]++
+		}
+		for _, r := range m.Reactions {
+			emoji[r.Emoji]++
+		}
+		day := dayOf(m.At, loc)
+		days[day]++
+		if postedOn[m.UserID] == nil {
+			postedOn[m.UserID] = map[time.Time]bool{}
+		}
+		postedOn[m.UserID][day] = true
+	}
+	w.Messages = len(inYear)
+	w.Members = len(posts)
+	if len(inYear) > 0 {
+		first, last := highlight(inYear[0]), highlight(inYear[len(inYear)-1])
+		w.First, w.Last = &first, &last
+	}
+
+	// Members are ranked by user ID, as several can go by the same name.
+	w.Active = rank(posts, top)
+	for i := range w.Active {
+		w.Active[i].Name = name(w.Active[i].Name)
+	}
+	w.Emoji = rank(emoji, top)
+
+	liked := []Message{}
+	for _, m := range inYear {
+		if m.Likes() > 0 {
+			liked = append(liked, m)
+		}
+	}
+	sort.SliceStable(liked, func(i, j int) bool { return liked[i].Likes() > liked[j].Likes() })
+	for i, m := range liked {
+		if top > 0 && i >= top {
+			break
+		}
+		w.Liked = append(w.Liked, highlight(m))
+	}
+
+	for day, count := range days {
+		w.Busiest = append(w.Busiest, Day{Date: day, Messages: count})
+	}
+	sort.Slice(w.Busiest, func(i, j int) bool {
+		if w.Busiest[i].Messages != w.Busiest[j].Messages {
+			return w.Busiest[i].Messages > w.Busiest[j].Messages
+		}
+		return w.Busiest[i].Date.Before(w.Busiest[j].Date)
+	})
+	if top > 0 && len(w.Busiest) > top {
+		w.Busiest = w.Busiest[:top]
+	}
+
+	for userID, dates := range postedOn {
+		s := longestStreak(dates)
+		s.Name = name(userID)
+		w.Streaks = append(w.Streaks, s)
+	}
+	sort.Slice(w.Streaks, func(i, j int) bool {
+		if w.Streaks[i].Days != w.Streaks[j].Days {
+			return w.Streaks[i].Days > w.Streaks[j].Days
+		}
+		return w.Streaks[i].Name < w.Streaks[j].Name
+	})
+	if top > 0 && len(w.Streaks) > top {
+		w.Streaks = w.Streaks[:top]
+	}
+
+	// Pairs are counted by user IDs too, and named once they are ranked.
+	type pair struct{ from, to string }
+	pairs := map[string]int{}
+	ends := map[string]pair{}
+	for _, i := range mentions {
+		if i.Kind != analytics.InteractionMention || i.At < window.Start || i.At >= window.End || i.From == i.To {
+			continue
+		}
+		key := i.From + " " + i.To
+		pairs[key] += i.Count
+		ends[key] = pair{i.From, i.To}
+	}
+	w.Mentions = rank(pairs, top)
+	for i := range w.Mentions {
+		p := ends[w.Mentions[i].Name]
+		w.Mentions[i].Name = name(p.from) + " → " + name(p.to)
+	}
+	return w
+}
+
+// dayOf is midnight of the day a time falls on.
+func dayOf(at int, loc *time.Location) time.Time {
+	t := time.Unix(int64(at), 0).In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// longestStreak finds the longest run of consecutive days, the earliest of
+// the longest when there are several.
+func longestStreak(dates map[time.Time]bool) Streak {
+	sorted := make([]time.Time, 0, len(dates))
+	for d := range dates {
+		sorted = append(sorted, d)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+
+	best := Streak{}
+	current := Streak{}
+	for _, d := range sorted {
+		// AddDate keeps consecutive days apart across daylight saving time.
+		if current.Days > 0 && current.End.AddDate(0, 0, 1).Equal(d) {
+			current.End = d
+			current.Days++
+		} else {
+			current = Streak{Days: 1, Start: d, End: d}
+		}
+		if current.Days > best.Days {
+			best = current
+		}
+	}
+	return best
+}
+
+// rank sorts counts from most to least, ties by name, keeping the top, or
+// all of them when top is 0.
+func rank(counts map[string]int, top int) []Ranked {
+	ranked := make([]Ranked, 0, len(counts))
+	for name, count := range counts {
+		ranked = append(ranked, Ranked{Name: name, Count: count})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Count != ranked[j].Count {
+			return ranked[i].Count > ranked[j].Count
+		}
+		return ranked[i].Name < ranked[j].Name
+	})
+	if top > 0 && len(ranked) > top {
+		ranked = ranked[:top]
+	}
+	return ranked
+}
+
+// Emoji finds the emoji in text. Skin tones, variation selectors, flags and
+// sequences joined with zero width joiners stay part of their emoji.
+func Emoji(text string) []string {
+	found := []string{}
+	var current strings.Builder
+	joined, flag := false, false
+	flush := func() {
+		if current.Len() > 0 {
+			found = append(found, current.String())
+			current.Reset()
+		}
+	}
+	for _, r := range text {
+		switch {
+		case r == '\u200d' && current.Len() > 0:
+			current.WriteRune(r)
+			joined = true
+		case (r == '\ufe0f' || (r >= 0x1f3fb && r <= 0x1f3ff)) && current.Len() > 0:
+			current.WriteRune(r)
+		case isRegionalIndicator(r) && flag:
+			// The second letter of a flag.
+			current.WriteRune(r)
+			flag = false
+		case unicode.Is(unicode.So, r) && r != '\ufffd':
+			if !joined {
+				flush()
+			}
+			current.WriteRune(r)
+			joined = false
+			flag = isRegionalIndicator(r)
+		default:
+			flush()
+			joined, flag = false, false
+		}
+	}
+	flush()
+	return found
+}
+
+// isRegionalIndicator reports whether r is one of the letters flags are
+// made of.
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
@@ -0,0 +1,117 @@
+package report
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"patrickwthomas.net/groupme-graph/analytics"
+)
+
+func TestEmoji(t *testing.T) {
+	got := Emoji("hi 😂😂 ❤️ 👍🏽 👨‍👩‍👧 🇺🇸🇨🇦 done")
+	want := []string{"😂", "😂", "❤️", "👍🏽", "👨‍👩‍👧", "🇺🇸", "🇨🇦"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if len(Emoji("no emoji, just text �")) != 0 {
+		t.Error("found emoji in plain text")
+	}
+}
+
+func TestNewWrapped(t *testing.T) {
+	loc := time.UTC
+	at := func(month time.Month, day, hour int) int {
+		return int(time.Date(2026, month, day, hour, 0, 0, 0, loc).Unix())
+	}
+	messages := []Message{
+		{ID: "0", UserID: "a", At: int(time.Date(2025, 12, 31, 23, 0, 0, 0, loc).Unix()), Text: "last year"},
+		{ID: "1", UserID: "a", At: at(1, 1, 9), Text: "happy new year 🎉", Reactions: []Reaction{{"b", "❤️"}}},
+		{ID: "2", UserID: "b", At: at(1, 2, 9), Text: "🎉🎉"},
+		{ID: "3", UserID: "a", At: at(1, 2, 10), Text: "hi", Reactions: []Reaction{{"b", "❤️"}, {"b", "😂"}, {"c", "❤️"}}},
+		{ID: "4", UserID: "a", At: at(1, 3, 9), Text: "again"},
+		{ID: "5", UserID: "b", At: at(3, 1, 9), Text: "bye"},
+	}
+	mentions := []analytics.Interaction{
+		{From: "a", To: "b", Kind: analytics.InteractionMention, At: at(1, 1, 9), Count: 1},
+		{From: "a", To: "b", Kind: analytics.InteractionMention, At: at(2, 1, 9), Count: 1},
+		{From: "b", To: "a", Kind: analytics.InteractionMention, At: at(2, 1, 9), Count: 1},
+		{From: "b", To: "a", Kind: analytics.InteractionReaction, At: at(2, 1, 9), Count: 1},
+	}
+	names := map[string]string{"a": "Ann"}
+
+	w := NewWrapped("Club", 2026, messages, mentions, names, loc, 2)
+	if w.Messages != 5 || w.Members != 2 {
+		t.Errorf("got %d messages from %d members", w.Messages, w.Members)
+	}
+	if !reflect.DeepEqual(w.Active, []Ranked{{"Ann", 3}, {"b", 2}}) {
+		t.Errorf("active %+v", w.Active)
+	}
+	if len(w.Liked) != 2 || w.Liked[0].ID != "3" || w.Liked[0].Likes() != 2 || w.Liked[1].ID != "1" {
+		t.Errorf("liked %+v", w.Liked)
+	}
+	if !reflect.DeepEqual(w.Emoji, []Ranked{{"❤️", 3}, {"🎉", 3}}) {
+		t.Errorf("emoji %+v", w.Emoji)
+	}
+	if len(w.Busiest) != 2 || w.Busiest[0].Messages != 2 || w.Busiest[0].Date.Day() != 2 {
+		t.Errorf("busiest %+v", w.Busiest)
+	}
+	if w.Streaks[0].Name != "Ann" || w.Streaks[0].Days != 3 || w.Streaks[1].Days != 1 {
+		t.Errorf("streaks %+v", w.Streaks)
+	}
+	if !reflect.DeepEqual(w.Mentions, []Ranked{{"Ann → b", 2}, {"b → Ann", 1}}) {
+		t.Errorf("mentions %+v", w.Mentions)
+	}
+	if w.First.ID != "1" || w.Last.ID != "5" || w.First.Name != "Ann" {
+		t.Errorf("first %+v, last %+v", w.First, w.Last)
+	}
+
+	var out bytes.Buffer
+	w.Last.Text = "<script>alert(1)</script>"
+	err := WriteHTML(&out, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := out.String()
+	if !strings.Contains(html, "Club 2026 Wrapped") || !strings.Contains(html, "Ann → b") || strings.Contains(html, "<script>") {
+		t.Errorf("bad report:\n%s", html)
+	}
+}
+
+func TestNewWrappedEmpty(t *testing.T) {
+	w := NewWrapped("Club", 2026, nil, nil, nil, time.UTC, 10)
+	if w.First != nil || w.Messages != 0 {
+		t.Errorf("got %+v", w)
+	}
+	var out bytes.Buffer
+	if err := WriteHTML(&out, w); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewWrappedAll(t *testing.T) {
+	at := int(time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC).Unix())
+	messages := []Message{
+		{ID: "1", UserID: "a", At: at},
+		{ID: "2", UserID: "b", At: at + 1},
+		{ID: "3", UserID: "c", At: at + 2},
+	}
+	mentions := []analytics.Interaction{
+		{From: "a", To: "c", Kind: analytics.InteractionMention, At: at, Count: 2},
+		{From: "b", To: "c", Kind: analytics.InteractionMention, At: at, Count: 1},
+	}
+	names := map[string]string{"a": "Sam", "b": "Sam"}
+
+	w := NewWrapped("Club", 2026, messages, mentions, names, time.UTC, 0)
+	if !reflect.DeepEqual(w.Active, []Ranked{{"Sam", 1}, {"Sam", 1}, {"c", 1}}) {
+		t.Errorf("active %+v", w.Active)
+	}
+	if !reflect.DeepEqual(w.Mentions, []Ranked{{"Sam → c", 2}, {"Sam → c", 1}}) {
+		t.Errorf("mentions %+v", w.Mentions)
+	}
+	if len(w.Streaks) != 3 {
+		t.Errorf("streaks %+v", w.Streaks)
+	}
+}
@@ -0,0 +1,187 @@
+package score
+
+import (
+	"sort"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/neo4j"
+	"patrickwthomas.net/groupme-graph/analytics"
+	"patrickwthomas.net/groupme-graph/database"
+	"patrickwthomas.net/groupme-graph/groupme"
+)
+
+const saveScoresQuery = `UNWIND $rows AS r
+MATCH (m:Message{ID: r.ID}) SET m += r.scores`
+
+// SaveScores rates messages with scorers and stores the scores on their
+// Message nodes. System messages are not rated.
+func SaveScores(driver *database.Neo4j, messages []groupme.Message, scorers []Scorer) error {
+	rows := []interface{}{}
+	for _, m := range messages {
+		if !m.System {
+			rows = append(rows, map[string]interface{}{"ID": m.ID, "scores": Scores(m.Text, scorers)})
+		}
+	}
+	if len(scorers) == 0 || len(rows) == 0 {
+		return nil
+	}
+
+	session, err := driver.NewWriteSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	_, err = session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(saveScoresQuery, map[string]interface{}{"rows": rows})
+		if err != nil {
+			return nil, err
+		}
+		return result.Consume()
+	})
+	return err
+}
+
+// Rescore rates the messages already in the database that are missing a
+// score, batchSize at a time, returning how many were rated.
+func Rescore(driver *database.Neo4j, scorers []Scorer, batchSize int) (int, error) {
+	properties := make([]string, len(scorers))
+	for i, s := range scorers {
+		properties[i] = s.Property()
+	}
+
+	total := 0
+	for {
+		messages, err := unscored(driver, properties, batchSize)
+		if err != nil || len(messages) == 0 {
+			return total, err
+		}
+		err = SaveScores(driver, messages, scorers)
+		if err != nil {
+			return total, err
+		}
+		total += len(messages)
+	}
+}
+
+func unscored(driver *database.Neo4j, properties []string, limit int) ([]groupme.Message, error) {
+	session, err := driver.NewReadSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	result, err := session.Run(`MATCH (m:Message)
+	WHERE coalesce(m.System, false) = false AND any(p IN $properties WHERE m[p] IS NULL)
+	RETURN m.ID, coalesce(m.Text, "") LIMIT $limit`, map[string]interface{}{"properties": properties, "limit": limit})
+	if err != nil {
+		return nil, err
+	}
+	messages := []groupme.Message{}
+	for result.Next() {
+		values := result.Record().Values()
+		m := groupme.Message{}
+		m.ID, _ = values[0].(string)
+		m.Text, _ = values[1].(string)
+		messages = append(messages, m)
+	}
+	return messages, result.Err()
+}
+
+// Sample is the score of a message.
+type Sample struct {
+	GroupID string
+	UserID  string
+	At      int
+	Score   float64
+}
+
+// LoadSamples loads the scores stored in a property, optionally only those of
+// a group.
+func LoadSamples(driver *database.Neo4j, property, groupID string) ([]Sample, error) {
+	session, err := driver.NewReadSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	result, err := session.Run(`MATCH (m:Message)
+	WHERE m[$property] IS NOT NULL AND ($group = "" OR m.GroupID = $group)
+	RETURN m.GroupID, m.UserID, m.CreatedAt, m[$property]`, map[string]interface{}{"property": property, "group": groupID})
+	if err != nil {
+		return nil, err
+	}
+	samples := []Sample{}
+	for result.Next() {
+		values := result.Record().Values()
+		s := Sample{}
+		s.GroupID, _ = values[0].(string)
+		s.UserID, _ = values[1].(string)
+		at, _ := values[2].(int64)
+		s.At = int(at)
+		s.Score, _ = values[3].(float64)
+		samples = append(samples, s)
+	}
+	return samples, result.Err()
+}
+
+// Aggregate is the scores of a set of messages summed up.
+type Aggregate struct {
+	Key   string
+	Count int
+	Mean  float64
+	// Positive and Negative are the shares of messages scoring above 0.05
+	// and below -0.05, VADER's thresholds.
+	Positive float64
+	Negative float64
+}
+
+// AggregateBy sums up samples by a key, e.g. their member. Aggregates are
+// sorted by key.
+func AggregateBy(samples []Sample, key func(Sample) string) []Aggregate {
+	byKey := map[string]*Aggregate{}
+	keys := []string{}
+	for _, s := range samples {
+		k := key(s)
+		a, ok := byKey[k]
+		if !ok {
+			a = &Aggregate{Key: k}
+			byKey[k] = a
+			keys = append(keys, k)
+		}
+		a.Count++
+		a.Mean += s.Score
+		if s.Score > 0.05 {
+			a.Positive++
+		} else if s.Score < -0.05 {
+			a.Negative++
+		}
+	}
+	sort.Strings(keys)
+
+	aggregates := make([]Aggregate, len(keys))
+	for i, k := range keys {
+		a := byKey[k]
+		n := float64(a.Count)
+		aggregates[i] = Aggregate{Key: k, Count: a.Count, Mean: a.Mean / n, Positive: a.Positive / n, Negative: a.Negative / n}
+	}
+	return aggregates
+}
+
+// ByGroup keys samples by group.
+func ByGroup(s Sample) string {
+	return s.GroupID
+}
+
+// ByMember keys samples by member.
+func ByMember(s Sample) string {
+	return s.UserID
+}
+
+// ByWeek keys samples by the Monday starting their week in a time zone.
+func ByWeek(loc *time.Location) func(Sample) string {
+	return func(s Sample) string {
+		w, _ := analytics.WindowOf(analytics.PeriodWeek, s.At, loc)
+		return w.Label(analytics.PeriodWeek, loc)
+	}
+}
@@ -0,0 +1,55 @@
+// Package score rates the text of messages with local models, such as the
+// built-in sentiment lexicon. Scores are stored as properties of Message
+// nodes named after their scorer.
+package score
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Scorer rates a text. Scorers must be safe for concurrent use.
+type Scorer interface {
+	// Property is the name of the Message property the score is stored in.
+	Property() string
+	// Score rates a text. Texts without anything to rate score 0.
+	Score(text string) float64
+}
+
+// factories create the scorers known by name.
+var factories = map[string]func() Scorer{
+	"sentiment": func() Scorer { return NewSentiment() },
+}
+
+// Register makes a scorer available by name, e.g. from settings.
+func Register(name string, factory func() Scorer) {
+	factories[name] = factory
+}
+
+// New creates the scorers with the given names.
+func New(names ...string) ([]Scorer, error) {
+	scorers := []Scorer{}
+	for _, name := range names {
+		factory, ok := factories[name]
+		if !ok {
+			known := []string{}
+			for n := range factories {
+				known = append(known, n)
+			}
+			sort.Strings(known)
+			return nil, fmt.Errorf("unknown scorer %q, known scorers are %s", name, strings.Join(known, ", "))
+		}
+		scorers = append(scorers, factory())
+	}
+	return scorers, nil
+}
+
+// Scores rates a text with every scorer, by property name.
+func Scores(text string, scorers []Scorer) map[string]interface{} {
+	scores := map[string]interface{}{}
+	for _, s := range scorers {
+		scores[s.Property()] = s.Score(text)
+	}
+	return scores
+}
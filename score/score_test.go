@@ -0,0 +1,91 @@
+package score
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSentiment(t *testing.T) {
+	s := NewSentiment()
+	cases := []struct {
+		text string
+		sign int
+	}{
+		{"this is great", 1},
+		{"this is terrible", -1},
+		{"the meeting is at 5", 0},
+		{"not bad at all", 1},
+		{"I don't like it", -1},
+		{"thanks :)", 1},
+		{"😭😭", -1},
+	}
+	for _, c := range cases {
+		score := s.Score(c.text)
+		if (c.sign > 0 && score <= 0.05) || (c.sign < 0 && score >= -0.05) || (c.sign == 0 && score != 0) {
+			t.Errorf("%q scored %v", c.text, score)
+		}
+		if score < -1 || score > 1 {
+			t.Errorf("%q scored %v outside [-1, 1]", c.text, score)
+		}
+	}
+}
+
+func TestSentimentModifiers(t *testing.T) {
+	s := NewSentiment()
+	plain := s.Score("the food was good")
+	stronger := []string{
+		"the food was very good",
+		"the food was GOOD",
+		"the food was good!!",
+	}
+	for _, text := range stronger {
+		if score := s.Score(text); score <= plain {
+			t.Errorf("%q scored %v, not more than %v", text, score, plain)
+		}
+	}
+	if score := s.Score("the food was barely good"); score >= plain {
+		t.Errorf("dampened score %v, not less than %v", score, plain)
+	}
+	if score := s.Score("the food was good but the service was terrible"); score >= 0 {
+		t.Errorf("but clause scored %v", score)
+	}
+}
+
+func TestNew(t *testing.T) {
+	scorers, err := New("sentiment")
+	if err != nil || len(scorers) != 1 || scorers[0].Property() != "sentiment" {
+		t.Errorf("got %v, %v", scorers, err)
+	}
+	if _, err := New("toxicity"); err == nil {
+		t.Error("unknown scorer created")
+	}
+
+	Register("length", func() Scorer { return lengthScorer{} })
+	scores := Scores("four", append(scorers, lengthScorer{}))
+	if scores["length"] != 4.0 || scores["sentiment"] != 0.0 {
+		t.Errorf("got %v", scores)
+	}
+}
+
+type lengthScorer struct{}
+
+func (lengthScorer) Property() string          { return "length" }
+func (lengthScorer) Score(text string) float64 { return float64(len(text)) }
+
+func TestAggregateBy(t *testing.T) {
+	monday := int(time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC).Unix())
+	samples := []Sample{
+		{GroupID: "1", UserID: "a", At: monday, Score: 0.5},
+		{GroupID: "1", UserID: "a", At: monday + 7*86400, Score: -0.5},
+		{GroupID: "1", UserID: "b", At: monday, Score: 0},
+	}
+
+	members := AggregateBy(samples, ByMember)
+	if len(members) != 2 || members[0].Key != "a" || members[0].Count != 2 || members[0].Mean != 0 || members[0].Positive != 0.5 || members[0].Negative != 0.5 {
+		t.Errorf("got %+v", members)
+	}
+	weeks := AggregateBy(samples, ByWeek(time.UTC))
+	if len(weeks) != 2 || weeks[0].Key != "2021-03-01" || weeks[0].Count != 2 {
+		t.Errorf("got %+v", weeks)
+	}
+}
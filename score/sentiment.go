@@ -0,0 +1,224 @@
+package score
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Sentiment is a lexicon based sentiment analyzer in the style of VADER.
+// Words have a valence from -4 to 4, which boosters, negations, capitals,
+// "but" and exclamation marks adjust. The score is the normalised sum of the
+// valences, from -1 (most negative) to 1 (most positive).
+type Sentiment struct {
+	Lexicon map[string]float64
+}
+
+// NewSentiment creates a sentiment analyzer with the built-in lexicon.
+func NewSentiment() *Sentiment {
+	return &Sentiment{Lexicon: sentimentLexicon}
+}
+
+// Property is the name of the sentiment property.
+func (s *Sentiment) Property() string {
+	return "sentiment"
+}
+
+// Constants from VADER.
+const (
+	boosterIncrement  = 0.293
+	capsIncrement     = 0.733
+	negationScalar    = -0.74
+	exclamationWeight = 0.292
+	questionWeight    = 0.18
+	normalisation     = 15
+)
+
+// Score rates the sentiment of a text from -1 to 1.
+func (s *Sentiment) Score(text string) float64 {
+	tokens := tokenize(text)
+	mixedCase := hasMixedCase(tokens)
+
+	valences := make([]float64, len(tokens))
+	for i, token := range tokens {
+		word := strings.ToLower(token)
+		v, ok := s.Lexicon[word]
+		if !ok || boosters[word] != 0 {
+			continue
+		}
+		if mixedCase && isShouted(token) {
+			v += math.Copysign(capsIncrement, v)
+		}
+		for distance := 1; distance <= 3 && i-distance >= 0; distance++ {
+			previous := tokens[i-distance]
+			if boost := boosters[strings.ToLower(previous)]; boost != 0 {
+				scalar := boost * (1 - 0.05*float64(distance-1))
+				if mixedCase && isShouted(previous) {
+					scalar += math.Copysign(capsIncrement, boost)
+				}
+				if v < 0 {
+					scalar = -scalar
+				}
+				v += scalar
+			}
+			if isNegation(previous) {
+				v *= negationScalar
+			}
+		}
+		valences[i] = v
+	}
+
+	// Whatever follows "but" matters more than what comes before.
+	for i, token := range tokens {
+		if strings.ToLower(token) == "but" {
+			for j := range valences {
+				if j < i {
+					valences[j] *= 0.5
+				} else if j > i {
+					valences[j] *= 1.5
+				}
+			}
+			break
+		}
+	}
+
+	sum := 0.0
+	for _, v := range valences {
+		sum += v
+	}
+	if sum == 0 {
+		return 0
+	}
+	sum += math.Copysign(punctuationEmphasis(text), sum)
+
+	score := sum / math.Sqrt(sum*sum+normalisation)
+	return math.Max(-1, math.Min(1, score))
+}
+
+// punctuationEmphasis is how much exclamation and question marks strengthen
+// the sentiment of a text.
+func punctuationEmphasis(text string) float64 {
+	exclamations := math.Min(float64(strings.Count(text, "!")), 4)
+	emphasis := exclamations * exclamationWeight
+	if questions := strings.Count(text, "?"); questions > 3 {
+		emphasis += 0.96
+	} else if questions > 1 {
+		emphasis += float64(questions) * questionWeight
+	}
+	return emphasis
+}
+
+// tokenize splits a text into words, trimming the punctuation around them
+// unless the token is an emoticon.
+func tokenize(text string) []string {
+	tokens := []string{}
+	for _, field := range strings.Fields(separateSymbols(text)) {
+		if _, ok := sentimentLexicon[field]; ok {
+			tokens = append(tokens, field)
+			continue
+		}
+		word := strings.TrimFunc(field, func(r rune) bool {
+			return unicode.IsPunct(r) && r != '\''
+		})
+		if word != "" {
+			tokens = append(tokens, word)
+		}
+	}
+	return tokens
+}
+
+// separateSymbols puts spaces around emoji and other symbols so that they are
+// tokens of their own, dropping variation selectors.
+func separateSymbols(text string) string {
+	b := strings.Builder{}
+	for _, r := range text {
+		if r == '\uFE0F' {
+			continue
+		} else if unicode.Is(unicode.So, r) {
+			b.WriteString(" " + string(r) + " ")
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// isShouted reports whether a word is written in capitals.
+func isShouted(word string) bool {
+	letters := 0
+	for _, r := range word {
+		if unicode.IsLower(r) {
+			return false
+		} else if unicode.IsUpper(r) {
+			letters++
+		}
+	}
+	return letters > 1
+}
+
+// hasMixedCase reports whether some but not all words are in capitals, which
+// is when capitals mean emphasis.
+func hasMixedCase(tokens []string) bool {
+	shouted := 0
+	for _, t := range tokens {
+		if isShouted(t) {
+			shouted++
+		}
+	}
+	return shouted > 0 && shouted < len(tokens)
+}
+
+func isNegation(word string) bool {
+	word = strings.ToLower(word)
+	return negations[word] || strings.HasSuffix(word, "n't")
+}
+
+var negations = map[string]bool{
+	"not": true, "no": true, "never": true, "none": true, "nobody": true, "nothing": true,
+	"neither": true, "nor": true, "nowhere": true, "cannot": true, "cant": true, "dont": true,
+	"doesnt": true, "didnt": true, "isnt": true, "wasnt": true, "wont": true, "without": true,
+}
+
+// boosters strengthen or weaken the word they precede.
+var boosters = map[string]float64{
+	"absolutely": boosterIncrement, "amazingly": boosterIncrement, "completely": boosterIncrement,
+	"really": boosterIncrement, "very": boosterIncrement, "so": boosterIncrement, "super": boosterIncrement,
+	"extremely": boosterIncrement, "incredibly": boosterIncrement, "totally": boosterIncrement,
+	"most": boosterIncrement, "more": boosterIncrement, "too": boosterIncrement, "hella": boosterIncrement,
+	"barely": -boosterIncrement, "hardly": -boosterIncrement, "slightly": -boosterIncrement,
+	"somewhat": -boosterIncrement, "kinda": -boosterIncrement, "sorta": -boosterIncrement,
+	"less": -boosterIncrement, "little": -boosterIncrement, "marginally": -boosterIncrement,
+}
+
+// sentimentLexicon is a small general purpose lexicon of valences, with the
+// values of VADER's lexicon for the words they share.
+var sentimentLexicon = map[string]float64{
+	// Positive words.
+	"good": 1.9, "great": 3.1, "awesome": 3.1, "amazing": 2.8, "excellent": 2.7, "fantastic": 2.6,
+	"wonderful": 2.7, "nice": 1.8, "cool": 1.3, "love": 3.2, "loved": 2.9, "lovely": 2.8, "like": 1.5,
+	"liked": 1.8, "happy": 2.7, "glad": 2.0, "fun": 2.3, "funny": 1.9, "best": 3.2, "better": 1.9,
+	"beautiful": 2.9, "perfect": 2.7, "thanks": 1.9, "thank": 1.5, "thx": 1.5, "ty": 1.6,
+	"congrats": 2.4, "congratulations": 2.9, "yay": 2.4, "win": 2.8, "won": 2.7, "excited": 1.4,
+	"exciting": 2.2, "proud": 2.1, "enjoy": 2.2, "enjoyed": 2.3, "welcome": 2.0, "sweet": 2.0,
+	"yes": 1.7, "ok": 1.2, "okay": 0.9, "wow": 2.8, "lol": 1.8, "lmao": 2.0, "haha": 2.0, "hahaha": 2.5,
+	"rofl": 2.7, "hope": 1.9, "helpful": 1.8, "kind": 2.4, "smart": 1.7, "brilliant": 2.8, "safe": 1.9,
+	"agree": 1.5, "yum": 2.0, "delicious": 2.7, "cute": 2.0, "friend": 2.2, "friends": 2.1,
+	"pleased": 1.9, "relieved": 1.6, "super": 2.9, "legend": 1.9, "incredible": 3.4, "impressive": 2.2,
+	"appreciate": 1.7, "appreciated": 2.3, "celebrate": 2.7, "free": 2.3, "easy": 1.9, "ready": 1.5,
+	// Negative words.
+	"bad": -2.5, "terrible": -2.1, "awful": -2.0, "horrible": -2.5, "worst": -3.1, "worse": -2.1,
+	"hate": -2.7, "hated": -3.2, "sad": -2.1, "angry": -2.3, "mad": -2.2, "annoying": -1.7,
+	"annoyed": -1.6, "sorry": -0.3, "sucks": -1.5, "suck": -1.9, "boring": -1.3, "bored": -1.1,
+	"stupid": -2.4, "dumb": -2.3, "ugly": -2.3, "wrong": -2.1, "fail": -2.5, "failed": -2.3,
+	"lost": -1.3, "lose": -1.7, "sick": -2.3, "hurt": -2.4, "pain": -2.3, "cry": -2.1, "crying": -2.1,
+	"scared": -1.9, "afraid": -2.2, "worried": -1.2, "worry": -1.9, "tired": -1.9, "ugh": -1.8,
+	"damn": -1.7, "shit": -2.6, "crap": -1.6, "wtf": -2.8, "disappointed": -1.9, "disappointing": -2.2,
+	"upset": -1.6, "problem": -1.7, "problems": -1.7, "broken": -2.1, "cancel": -1.0, "cancelled": -1.0,
+	"late": -0.6, "no": -1.2, "miss": -0.6, "kill": -3.7, "dead": -3.3, "die": -2.9, "rip": -1.5,
+	"idiot": -2.3, "jerk": -2.2, "rude": -2.0, "gross": -2.1, "nasty": -2.6, "unfair": -2.1,
+	"lonely": -1.5, "stress": -1.8, "stressed": -1.4, "confused": -1.3, "ruined": -2.4, "trash": -1.8,
+	// Emoticons and emoji.
+	":)": 2.0, ":-)": 1.3, ":D": 2.3, ":-D": 2.3, ";)": 0.9, "<3": 1.9, ":(": -1.9, ":-(": -1.5,
+	":'(": -2.2, ">:(": -2.8, "😀": 2.2, "😂": 2.0, "🤣": 2.2, "😊": 2.3, "😍": 2.8, "❤": 3.0,
+	"👍": 1.7, "🎉": 2.5, "😢": -2.1, "😭": -2.2, "😡": -2.9, "😠": -2.4, "👎": -1.7, "💔": -2.6,
+}
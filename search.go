@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"patrickwthomas.net/groupme-graph/export"
+	"patrickwthomas.net/groupme-graph/groupme"
+	"patrickwthomas.net/groupme-graph/search"
+)
+
+// searchDateLayout is the layout of the date flags of the search command.
+const searchDateLayout = "2006-01-02"
+
+func searchCommand(args []string) {
+	flags := flag.NewFlagSet("search", flag.ExitOnError)
+	groupID := flags.String("group", "", "only search the messages of this group ID")
+	userID := flags.String("member", "", "only search the messages of this user ID")
+	since := flags.String("since", "", "only search messages sent on or after this day (YYYY-MM-DD)")
+	until := flags.String("until", "", "only search messages sent on or before this day (YYYY-MM-DD)")
+	limit := flags.Int("limit", search.DefaultLimit, "maximum number of results")
+	context := flags.Int("context", 0, "number of surrounding messages shown with each result")
+	groups := flags.Bool("groups", false, "search group names and descriptions instead of messages")
+	exportName := flags.String("export", "", "search a data export archive instead of Neo4j")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: search [flags] <lucene query>\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	q := search.Query{
+		Text:    strings.Join(flags.Args(), " "),
+		GroupID: *groupID,
+		UserID:  *userID,
+		Limit:   *limit,
+		Context: *context,
+	}
+	if *since != "" {
+		day, err := time.ParseInLocation(searchDateLayout, *since, time.Local)
+		if err != nil {
+			log.Panic(err)
+		}
+		q.Since = int(day.Unix())
+	}
+	if *until != "" {
+		day, err := time.ParseInLocation(searchDateLayout, *until, time.Local)
+		if err != nil {
+			log.Panic(err)
+		}
+		q.Until = int(day.AddDate(0, 0, 1).Unix())
+	}
+
+	catalog := emojiCatalog(readSettings())
+	var searcher search.Searcher
+	if *exportName != "" {
+		searcher = loadExport(*exportName, catalog)
+	} else {
+		searcher = &search.Neo4j{Driver: connectNeo4j(), Emoji: catalog}
+	}
+
+	if *groups {
+		hits, err := searcher.Groups(q.Text, q.Limit)
+		if err != nil {
+			log.Panic(err)
+		}
+		for _, hit := range hits {
+			fmt.Printf("%s  %s (%.2f)\n", hit.Group.ID, hit.Group.Name, hit.Score)
+			if hit.Group.Description != "" {
+				fmt.Printf("    %s\n", hit.Group.Description)
+			}
+		}
+		return
+	}
+
+	hits, err := searcher.Messages(q)
+	if err != nil {
+		log.Panic(err)
+	}
+	for i, hit := range hits {
+		if i > 0 && q.Context > 0 {
+			fmt.Println("--")
+		}
+		for _, m := range hit.Before {
+			printSearchMessage("  ", m, "")
+		}
+		printSearchMessage("> ", hit.Message, fmt.Sprintf("  [%s, %.2f]", hit.GroupName, hit.Score))
+		for _, m := range hit.After {
+			printSearchMessage("  ", m, "")
+		}
+	}
+}
+
+// loadExport reads a data export archive into an in-memory index.
+func loadExport(name string, catalog groupme.EmojiCatalog) *search.Memory {
+	archive, err := export.Open(name)
+	if err != nil {
+		log.Panic(err)
+	}
+	defer archive.Close()
+
+	index := search.NewMemory()
+	index.Emoji = catalog
+	_, err = archive.Import(index, nil)
+	if err != nil {
+		log.Panic(err)
+	}
+	return index
+}
+
+func printSearchMessage(prefix string, m groupme.Message, suffix string) {
+	at := time.Unix(int64(m.CreatedAt), 0).Format("2006-01-02 15:04")
+	fmt.Printf("%s%s %s: %s%s\n", prefix, at, m.Name, m.Text, suffix)
+}
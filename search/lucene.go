@@ -0,0 +1,152 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// clause is a single term or phrase of a Lucene query. Optional clauses only
+// add to the score, unless a query has no required clauses, in which case at
+// least one of them must match.
+type clause struct {
+	terms      []string
+	prefix     bool
+	required   bool
+	prohibited bool
+}
+
+// luceneQuery is the subset of the Lucene query syntax understood in memory:
+// terms, "phrases", trailing * wildcards, +required and -prohibited clauses,
+// and the AND, OR and NOT operators. Field names such as Text: are ignored.
+type luceneQuery []clause
+
+// parseLucene parses a query the way Neo4j's standard analyzer would see it.
+func parseLucene(text string) (luceneQuery, error) {
+	tokens, err := splitQuery(text)
+	if err != nil {
+		return nil, err
+	}
+
+	q := luceneQuery{}
+	required, prohibited, and := false, false, false
+	for _, token := range tokens {
+		switch token {
+		case "AND", "&&":
+			if len(q) > 0 && !q[len(q)-1].prohibited {
+				q[len(q)-1].required = true
+			}
+			and = true
+			continue
+		case "OR", "||":
+			continue
+		case "NOT", "!":
+			prohibited = true
+			continue
+		}
+
+		if strings.HasPrefix(token, "+") {
+			required, token = true, token[1:]
+		} else if strings.HasPrefix(token, "-") {
+			prohibited, token = true, token[1:]
+		}
+		if i := strings.Index(token, ":"); i != -1 && !strings.HasPrefix(token, `"`) {
+			token = token[i+1:]
+		}
+
+		c := clause{required: (required || and) && !prohibited, prohibited: prohibited}
+		if strings.HasSuffix(token, "*") && !strings.HasPrefix(token, `"`) {
+			c.prefix = true
+			token = strings.TrimSuffix(token, "*")
+		}
+		c.terms = analyze(strings.Trim(token, `"`))
+		if len(c.terms) > 0 {
+			q = append(q, c)
+		}
+		required, prohibited, and = false, false, false
+	}
+	if len(q) == 0 {
+		return nil, fmt.Errorf("query %q has no terms", text)
+	}
+	return q, nil
+}
+
+// splitQuery splits a query on spaces, keeping quoted phrases together.
+func splitQuery(text string) ([]string, error) {
+	tokens := []string{}
+	current := strings.Builder{}
+	quoted := false
+	for _, r := range text {
+		if r == '"' {
+			quoted = !quoted
+		}
+		if unicode.IsSpace(r) && !quoted {
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+			continue
+		}
+		current.WriteRune(r)
+	}
+	if quoted {
+		return nil, fmt.Errorf("query %q has an unterminated phrase", text)
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+	return tokens, nil
+}
+
+// analyze splits text into lower case words, like Lucene's standard analyzer.
+func analyze(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// score scores analyzed text against the query, returning 0 when it does not
+// match.
+func (q luceneQuery) score(words []string) float64 {
+	score := 0.0
+	hasRequired := false
+	for _, c := range q {
+		n := c.count(words)
+		if c.prohibited {
+			if n > 0 {
+				return 0
+			}
+			continue
+		}
+		if c.required {
+			hasRequired = true
+			if n == 0 {
+				return 0
+			}
+		}
+		score += float64(n)
+	}
+	if score == 0 && !hasRequired {
+		return 0
+	}
+	return score
+}
+
+// count counts the occurrences of the clause in analyzed text.
+func (c clause) count(words []string) int {
+	n := 0
+	for i := 0; i+len(c.terms) <= len(words); i++ {
+		match := true
+		for j, term := range c.terms {
+			last := j == len(c.terms)-1
+			if words[i+j] != term && !(c.prefix && last && strings.HasPrefix(words[i+j], term)) {
+				match = false
+				break
+			}
+		}
+		if match {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,168 @@
+package search
+
+import (
+	"math"
+	"sort"
+
+	"patrickwthomas.net/groupme-graph/groupme"
+)
+
+// Memory is a Searcher over groups and messages held in memory, for data that
+// is not in Neo4j such as a data export. It can be filled like a crawl store.
+type Memory struct {
+	// Emoji names GroupMe emoji in the text shown in results. It may be nil.
+	Emoji groupme.EmojiCatalog
+
+	groups   map[string]groupme.Group
+	messages map[string][]groupme.Message
+	words    map[string][]string
+	sorted   map[string]bool
+}
+
+// NewMemory creates an empty in-memory index.
+func NewMemory() *Memory {
+	return &Memory{
+		groups:   map[string]groupme.Group{},
+		messages: map[string][]groupme.Message{},
+		words:    map[string][]string{},
+		sorted:   map[string]bool{},
+	}
+}
+
+// SaveGroup adds a group to the index.
+func (s *Memory) SaveGroup(group groupme.Group) error {
+	s.groups[group.ID] = group
+	return nil
+}
+
+// SaveMessages adds messages to the index.
+func (s *Memory) SaveMessages(messages []groupme.Message) error {
+	for _, m := range messages {
+		if _, ok := s.words[m.ID]; ok {
+			continue
+		}
+		s.words[m.ID] = analyze(m.RenderText(s.Emoji))
+		s.messages[m.GroupID] = append(s.messages[m.GroupID], m)
+		s.sorted[m.GroupID] = false
+	}
+	return nil
+}
+
+// history returns the messages of a group, oldest first.
+func (s *Memory) history(groupID string) []groupme.Message {
+	messages := s.messages[groupID]
+	if !s.sorted[groupID] {
+		sort.SliceStable(messages, func(i, j int) bool {
+			if messages[i].CreatedAt != messages[j].CreatedAt {
+				return messages[i].CreatedAt < messages[j].CreatedAt
+			}
+			return groupme.CompareIDs(messages[i].ID, messages[j].ID) < 0
+		})
+		s.sorted[groupID] = true
+	}
+	return messages
+}
+
+// Messages searches the text of messages.
+func (s *Memory) Messages(q Query) ([]Hit, error) {
+	parsed, err := parseLucene(q.Text)
+	if err != nil {
+		return nil, err
+	}
+
+	type found struct {
+		groupID string
+		index   int
+		score   float64
+	}
+	results := []found{}
+	for groupID := range s.messages {
+		for i, m := range s.history(groupID) {
+			if !q.filter(m) {
+				continue
+			}
+			words := s.words[m.ID]
+			if score := parsed.score(words); score > 0 {
+				results = append(results, found{groupID, i, score / math.Sqrt(float64(len(words)))})
+			}
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+		return s.messages[results[i].groupID][results[i].index].CreatedAt > s.messages[results[j].groupID][results[j].index].CreatedAt
+	})
+	if len(results) > q.limit() {
+		results = results[:q.limit()]
+	}
+
+	hits := make([]Hit, len(results))
+	for i, r := range results {
+		history := s.messages[r.groupID]
+		hits[i] = Hit{
+			Message:   s.render(history[r.index]),
+			GroupName: s.groups[r.groupID].Name,
+			Score:     r.score,
+			Before:    s.renderAll(history[maxInt(0, r.index-q.Context):r.index]),
+			After:     s.renderAll(history[r.index+1 : minInt(len(history), r.index+1+q.Context)]),
+		}
+	}
+	return hits, nil
+}
+
+// Groups searches the names and descriptions of groups.
+func (s *Memory) Groups(text string, limit int) ([]GroupHit, error) {
+	parsed, err := parseLucene(text)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	hits := []GroupHit{}
+	for _, g := range s.groups {
+		if score := parsed.score(analyze(g.Name + " " + g.Description)); score > 0 {
+			hits = append(hits, GroupHit{Group: g, Score: score})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Group.Name < hits[j].Group.Name
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// render replaces the emoji placeholders of a message with their names.
+func (s *Memory) render(m groupme.Message) groupme.Message {
+	m.Text = m.RenderText(s.Emoji)
+	return m
+}
+
+func (s *Memory) renderAll(messages []groupme.Message) []groupme.Message {
+	rendered := make([]groupme.Message, len(messages))
+	for i, m := range messages {
+		rendered[i] = s.render(m)
+	}
+	return rendered
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,168 @@
+package search
+
+import (
+	"patrickwthomas.net/groupme-graph/database"
+	"patrickwthomas.net/groupme-graph/groupme"
+)
+
+// Full-text indexes created by database.Init.
+const (
+	messageIndex = "messageText"
+	groupIndex   = "groupText"
+)
+
+// messageFields are the returned fields of a message, in the order read by
+// readMessage. The placeholder and charmap of its emoji attachment come last.
+const messageFields = `m.ID, m.CreatedAt, m.UserID, m.GroupID, m.Name, m.Text, m.System,
+	[(m)-[:HAS_ATTACHMENT]->(a:Attachment{Type: "emoji"}) | [a.Placeholder, a.Charmap]]`
+
+const (
+	searchMessagesQuery = `CALL db.index.fulltext.queryNodes($index, $text) YIELD node AS m, score
+	WHERE ($group = "" OR m.GroupID = $group) AND ($user = "" OR m.UserID = $user)
+		AND ($since = 0 OR m.CreatedAt >= $since) AND ($until = 0 OR m.CreatedAt < $until)
+	OPTIONAL MATCH (g:Group{ID: m.GroupID})
+	RETURN ` + messageFields + `, g.Name, score
+	ORDER BY score DESC, m.CreatedAt DESC LIMIT $limit`
+
+	messagesBeforeQuery = `MATCH (m:Message{GroupID: $group})
+	WHERE m.CreatedAt < $at OR (m.CreatedAt = $at AND m.ID < $id)
+	RETURN ` + messageFields + `
+	ORDER BY m.CreatedAt DESC, m.ID DESC LIMIT $count`
+
+	messagesAfterQuery = `MATCH (m:Message{GroupID: $group})
+	WHERE m.CreatedAt > $at OR (m.CreatedAt = $at AND m.ID > $id)
+	RETURN ` + messageFields + `
+	ORDER BY m.CreatedAt, m.ID LIMIT $count`
+
+	searchGroupsQuery = `CALL db.index.fulltext.queryNodes($index, $text) YIELD node AS g, score
+	RETURN g.ID, g.Name, g.Description, g.Type, score
+	ORDER BY score DESC LIMIT $limit`
+)
+
+// Neo4j searches the full-text indexes of the database.
+type Neo4j struct {
+	Driver *database.Neo4j
+	// Emoji names the GroupMe emoji in the text of messages. Optional.
+	Emoji groupme.EmojiCatalog
+}
+
+// Messages searches the text of messages.
+func (s *Neo4j) Messages(q Query) ([]Hit, error) {
+	session, err := s.Driver.NewReadSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	result, err := session.Run(searchMessagesQuery, map[string]interface{}{
+		"index": messageIndex,
+		"text":  q.Text,
+		"group": q.GroupID,
+		"user":  q.UserID,
+		"since": q.Since,
+		"until": q.Until,
+		"limit": q.limit(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hits := []Hit{}
+	for result.Next() {
+		values := result.Record().Values()
+		hit := Hit{Message: s.render(readMessage(values))}
+		hit.GroupName, _ = values[8].(string)
+		hit.Score, _ = values[9].(float64)
+		hits = append(hits, hit)
+	}
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+
+	if q.Context > 0 {
+		for i := range hits {
+			m := hits[i].Message
+			hits[i].Before, err = s.surrounding(messagesBeforeQuery, m, q.Context)
+			if err != nil {
+				return nil, err
+			}
+			for l, r := 0, len(hits[i].Before)-1; l < r; l, r = l+1, r-1 {
+				hits[i].Before[l], hits[i].Before[r] = hits[i].Before[r], hits[i].Before[l]
+			}
+			hits[i].After, err = s.surrounding(messagesAfterQuery, m, q.Context)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return hits, nil
+}
+
+// surrounding runs one of the queries for the context of a message.
+func (s *Neo4j) surrounding(query string, m groupme.Message, count int) ([]groupme.Message, error) {
+	session, err := s.Driver.NewReadSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	result, err := session.Run(query, map[string]interface{}{"group": m.GroupID, "at": m.CreatedAt, "id": m.ID, "count": count})
+	if err != nil {
+		return nil, err
+	}
+	messages := []groupme.Message{}
+	for result.Next() {
+		messages = append(messages, s.render(readMessage(result.Record().Values())))
+	}
+	return messages, result.Err()
+}
+
+// render writes the GroupMe emoji of a message by name.
+func (s *Neo4j) render(m groupme.Message) groupme.Message {
+	m.Text = m.RenderText(s.Emoji)
+	return m
+}
+
+// Groups searches the names and descriptions of groups.
+func (s *Neo4j) Groups(text string, limit int) ([]GroupHit, error) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	session, err := s.Driver.NewReadSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	result, err := session.Run(searchGroupsQuery, map[string]interface{}{"index": groupIndex, "text": text, "limit": limit})
+	if err != nil {
+		return nil, err
+	}
+	hits := []GroupHit{}
+	for result.Next() {
+		values := result.Record().Values()
+		hit := GroupHit{}
+		hit.Group.ID, _ = values[0].(string)
+		hit.Group.Name, _ = values[1].(string)
+		hit.Group.Description, _ = values[2].(string)
+		hit.Group.Type, _ = values[3].(string)
+		hit.Score, _ = values[4].(float64)
+		hits = append(hits, hit)
+	}
+	return hits, result.Err()
+}
+
+// readMessage reads the messageFields of a record.
+func readMessage(values []interface{}) groupme.Message {
+	m := groupme.Message{}
+	m.ID, _ = values[0].(string)
+	createdAt, _ := values[1].(int64)
+	m.CreatedAt = int(createdAt)
+	m.UserID, _ = values[2].(string)
+	m.GroupID, _ = values[3].(string)
+	m.Name, _ = values[4].(string)
+	m.Text, _ = values[5].(string)
+	m.System, _ = values[6].(bool)
+	m.Attachments = groupme.EmojiAttachments(values[7])
+	return m
+}
@@ -0,0 +1,60 @@
+// Package search finds messages by their text, and groups by their name and
+// description, either in Neo4j's full-text indexes or in memory.
+package search
+
+import "patrickwthomas.net/groupme-graph/groupme"
+
+// Query is a search for messages. Text uses the Lucene query syntax. The other
+// fields narrow the results down and are ignored when empty.
+type Query struct {
+	Text    string
+	GroupID string
+	UserID  string
+	// Since and Until bound the creation time of messages, as unix times.
+	// Until is exclusive.
+	Since int
+	Until int
+	Limit int
+	// Context is the number of messages shown before and after each result.
+	Context int
+}
+
+// Hit is a message found by a search, best matches first.
+type Hit struct {
+	Message   groupme.Message
+	GroupName string
+	Score     float64
+	// Before and After are the surrounding messages of the group, oldest first.
+	Before []groupme.Message
+	After  []groupme.Message
+}
+
+// GroupHit is a group found by a search.
+type GroupHit struct {
+	Group groupme.Group
+	Score float64
+}
+
+// Searcher searches messages and groups.
+type Searcher interface {
+	Messages(q Query) ([]Hit, error)
+	Groups(text string, limit int) ([]GroupHit, error)
+}
+
+// DefaultLimit is the number of results when a query does not set one.
+const DefaultLimit = 20
+
+func (q Query) limit() int {
+	if q.Limit <= 0 {
+		return DefaultLimit
+	}
+	return q.Limit
+}
+
+// filter reports whether a message passes the filters of the query.
+func (q Query) filter(m groupme.Message) bool {
+	return (q.GroupID == "" || m.GroupID == q.GroupID) &&
+		(q.UserID == "" || m.UserID == q.UserID) &&
+		(q.Since == 0 || m.CreatedAt >= q.Since) &&
+		(q.Until == 0 || m.CreatedAt < q.Until)
+}
@@ -0,0 +1,101 @@
+package search
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"patrickwthomas.net/groupme-graph/groupme"
+)
+
+func testMemory() *Memory {
+	s := NewMemory()
+	s.SaveGroup(groupme.Group{ID: "1", Name: "Hiking Club", Description: "Trails and mountains"})
+	s.SaveGroup(groupme.Group{ID: "2", Name: "Book Club"})
+	texts := []string{
+		"who wants to go hiking saturday",
+		"the mountain trail was closed",
+		"Hiking is cancelled, rain",
+		"pizza after?",
+		"I'm reading about mountains",
+	}
+	messages := []groupme.Message{}
+	for i, text := range texts {
+		messages = append(messages, groupme.Message{ID: fmt.Sprint(i + 1), GroupID: "1", UserID: fmt.Sprint(10 + i%2), CreatedAt: 1000 + i*60, Text: text})
+	}
+	s.SaveMessages(messages)
+	s.SaveMessages([]groupme.Message{{ID: "9", GroupID: "2", UserID: "10", CreatedAt: 1000, Text: "hiking books?"}})
+	return s
+}
+
+func hitIDs(hits []Hit) []string {
+	ids := []string{}
+	for _, h := range hits {
+		ids = append(ids, h.Message.ID)
+	}
+	return ids
+}
+
+func TestMemoryMessages(t *testing.T) {
+	s := testMemory()
+	cases := []struct {
+		query Query
+		ids   []string
+	}{
+		{Query{Text: "pizza"}, []string{"4"}},
+		{Query{Text: "hiking"}, []string{"9", "3", "1"}},
+		{Query{Text: "hiking", GroupID: "1"}, []string{"3", "1"}},
+		{Query{Text: "hiking", UserID: "11"}, []string{}},
+		{Query{Text: "hiking", Since: 1060, Until: 1200}, []string{"3"}},
+		{Query{Text: "hiking AND rain"}, []string{"3"}},
+		{Query{Text: "hiking -rain", GroupID: "1"}, []string{"1"}},
+		{Query{Text: "mountain*"}, []string{"5", "2"}},
+		{Query{Text: `"trail was closed"`}, []string{"2"}},
+		{Query{Text: `"was trail"`}, []string{}},
+		{Query{Text: "Text:pizza OR rain"}, []string{"4", "3"}},
+	}
+
+	for _, c := range cases {
+		hits, err := s.Messages(c.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := hitIDs(hits); !reflect.DeepEqual(ids, c.ids) {
+			t.Errorf("%+v: got %v, want %v", c.query, ids, c.ids)
+		}
+	}
+}
+
+func TestMemoryContext(t *testing.T) {
+	s := testMemory()
+	hits, err := s.Messages(Query{Text: "rain", Context: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || hits[0].GroupName != "Hiking Club" {
+		t.Fatalf("got %+v", hits)
+	}
+	before, after := hits[0].Before, hits[0].After
+	if len(before) != 2 || before[0].ID != "1" || before[1].ID != "2" || len(after) != 2 || after[0].ID != "4" {
+		t.Errorf("bad context %+v %+v", before, after)
+	}
+}
+
+func TestMemoryGroups(t *testing.T) {
+	s := testMemory()
+	hits, err := s.Groups("club mountains", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 2 || hits[0].Group.ID != "1" {
+		t.Errorf("got %+v", hits)
+	}
+}
+
+func TestParseLuceneErrors(t *testing.T) {
+	for _, text := range []string{"", `"unterminated`, "AND"} {
+		if _, err := parseLucene(text); err == nil {
+			t.Errorf("%q parsed", text)
+		}
+	}
+}
@@ -0,0 +1,838 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"patrickwthomas.net/groupme-graph/analytics"
+	"patrickwthomas.net/groupme-graph/database"
+	"patrickwthomas.net/groupme-graph/groupme"
+	"patrickwthomas.net/groupme-graph/keywords"
+	"patrickwthomas.net/groupme-graph/local"
+	"patrickwthomas.net/groupme-graph/score"
+)
+
+// statsCommands are the subcommands of the stats command.
+var statsCommands = map[string]command{
+	"activity":    {"count messages by hour of the week, day, week or month", activityCommand},
+	"centrality":  {"rank the members of each group by how central they are", centralityCommand},
+	"communities": {"find the communities of members within each group", communitiesCommand},
+	"diff":        {"compare the interaction graphs of two time windows", diffCommand},
+	"links":       {"rank the domains shared in each group", linksCommand},
+	"overlap":     {"find groups sharing members and the members bridging them", overlapCommand},
+	"sentiment":   {"average the sentiment of messages by group, member and week", sentimentCommand},
+	"sessions":    {"split groups into conversations and time how fast members answer", sessionsCommand},
+	"snapshots":   {"save the interaction graph of each time window", snapshotsCommand},
+	"terms":       {"find the keywords of each group by month or member", termsCommand},
+}
+
+func statsCommand(args []string) {
+	if len(args) == 0 {
+		printStatsUsage()
+		os.Exit(2)
+	}
+	cmd, ok := statsCommands[args[0]]
+	if !ok {
+		printStatsUsage()
+		os.Exit(2)
+	}
+	cmd.run(args[1:])
+}
+
+func printStatsUsage() {
+	names := []string{}
+	for name := range statsCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(os.Stderr, "Usage: %s stats <statistic> [flags]\n\nStatistics:\n", os.Args[0])
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", name, statsCommands[name].usage)
+	}
+}
+
+// statsGroups lists the groups statistics are computed for.
+func statsGroups(driver *database.Neo4j, groupID string) []analytics.GroupInfo {
+	groups, err := analytics.LoadGroups(driver, groupID)
+	if err != nil {
+		log.Panic(err)
+	}
+	if len(groups) == 0 {
+		log.Panicf("no group found with ID %q", groupID)
+	}
+	return groups
+}
+
+func centralityCommand(args []string) {
+	flags := flag.NewFlagSet("stats centrality", flag.ExitOnError)
+	groupID := flags.String("group", "", "only rank the members of this group ID")
+	top := flags.Int("top", 10, "number of members shown per group, 0 for all")
+	write := flags.Bool("write", true, "write the scores into Centrality nodes")
+	flags.Parse(args)
+
+	driver := connectNeo4j()
+	now := int(time.Now().Unix())
+	for _, group := range statsGroups(driver, *groupID) {
+		interactions, err := analytics.LoadInteractions(driver, group.ID)
+		if err != nil {
+			log.Panic(err)
+		}
+		names, err := analytics.LoadMemberNames(driver, group.ID)
+		if err != nil {
+			log.Panic(err)
+		}
+		centralities := analytics.Centralities(analytics.BuildGraph(interactions))
+
+		fmt.Printf("%s (%d interactions)\n", group.Name, len(interactions))
+		table := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(table, "#\tMember\tDegree\tPageRank\tBetweenness\tEigenvector")
+		for i, c := range centralities {
+			if *top > 0 && i >= *top {
+				break
+			}
+			fmt.Fprintf(table, "%d\t%s\t%.0f\t%.4f\t%.4f\t%.4f\n", i+1, memberName(names, c.UserID), c.Degree, c.PageRank, c.Betweenness, c.Eigenvector)
+		}
+		table.Flush()
+		fmt.Println()
+
+		if *write {
+			err = analytics.SaveCentralities(driver, group.ID, centralities, now)
+			if err != nil {
+				log.Panic(err)
+			}
+		}
+	}
+}
+
+// memberName is the name of a member, or their user ID when it is unknown.
+func memberName(names map[string]string, userID string) string {
+	if name := names[userID]; name != "" {
+		return name
+	}
+	return userID
+}
+
+// statsLocation is the time zone statistics are computed in: the one given
+// on the command line, else the one from the settings, else the system one.
+func statsLocation(settings *local.Settings, name string) *time.Location {
+	if name == "" {
+		name = settings.TimeZone
+	}
+	if name == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		log.Panic(err)
+	}
+	return loc
+}
+
+func communitiesCommand(args []string) {
+	flags := flag.NewFlagSet("stats communities", flag.ExitOnError)
+	groupID := flags.String("group", "", "only find the communities of this group ID")
+	period := flags.String("window", analytics.PeriodAll, "split time into windows of a day, week, month, quarter, year or all")
+	tz := flags.String("tz", "", "time zone of the windows, defaults to time_zone from the settings")
+	write := flags.Bool("write", true, "write the communities into IN_COMMUNITY edges")
+	flags.Parse(args)
+
+	loc := statsLocation(readSettings(), *tz)
+	driver := connectNeo4j()
+	now := int(time.Now().Unix())
+	for _, group := range statsGroups(driver, *groupID) {
+		interactions, err := analytics.LoadInteractions(driver, group.ID)
+		if err != nil {
+			log.Panic(err)
+		}
+		names, err := analytics.LoadMemberNames(driver, group.ID)
+		if err != nil {
+			log.Panic(err)
+		}
+		windows, err := analytics.SplitInteractions(interactions, *period, loc)
+		if err != nil {
+			log.Panic(err)
+		}
+
+		fmt.Printf("%s\n", group.Name)
+		for _, w := range windows {
+			communities := analytics.Louvain(analytics.BuildGraph(w.Interactions))
+			fmt.Printf("  %s: %d communities, modularity %.3f\n", w.Window.Label(*period, loc), len(communities.Members()), communities.Modularity)
+			for i, members := range communities.Members() {
+				memberNames := make([]string, len(members))
+				for j, userID := range members {
+					memberNames[j] = memberName(names, userID)
+				}
+				fmt.Printf("    %d. %s\n", i+1, strings.Join(memberNames, ", "))
+			}
+
+			if *write {
+				err = analytics.SaveCommunities(driver, group.ID, *period, w.Window, communities, now)
+				if err != nil {
+					log.Panic(err)
+				}
+			}
+		}
+		fmt.Println()
+	}
+}
+
+// activitySeries is a set of posts the activity command reports on.
+type activitySeries struct {
+	group  analytics.GroupInfo
+	userID string
+	title  string
+	posts  []analytics.Post
+}
+
+func activityCommand(args []string) {
+	flags := flag.NewFlagSet("stats activity", flag.ExitOnError)
+	groupID := flags.String("group", "", "only count the messages of this group ID")
+	userID := flags.String("member", "", "only count the messages of this user ID")
+	perMember := flags.Bool("per-member", false, "report on every member of each group separately")
+	by := flags.String("by", "hour", "count by hour of the week, day, week or month")
+	format := flags.String("format", "terminal", "output as terminal, csv or svg")
+	out := flags.String("out", ".", "directory SVG charts are written to")
+	tz := flags.String("tz", "", "time zone of the counts, defaults to time_zone from the settings")
+	flags.Parse(args)
+	switch *by {
+	case "hour", analytics.PeriodDay, analytics.PeriodWeek, analytics.PeriodMonth:
+	default:
+		log.Panicf("unknown period %q", *by)
+	}
+	switch *format {
+	case "terminal", "csv", "svg":
+	default:
+		log.Panicf("unknown format %q", *format)
+	}
+
+	loc := statsLocation(readSettings(), *tz)
+	driver := connectNeo4j()
+
+	series := []activitySeries{}
+	for _, group := range statsGroups(driver, *groupID) {
+		posts, err := analytics.LoadPosts(driver, group.ID)
+		if err != nil {
+			log.Panic(err)
+		}
+		byUser := analytics.ByUser(posts)
+		if *userID != "" {
+			posts = byUser[*userID]
+			if len(posts) == 0 {
+				continue
+			}
+		}
+		if !*perMember || *userID != "" {
+			series = append(series, activitySeries{group, *userID, group.Name, posts})
+			continue
+		}
+
+		names, err := analytics.LoadMemberNames(driver, group.ID)
+		if err != nil {
+			log.Panic(err)
+		}
+		userIDs := []string{}
+		for id := range byUser {
+			userIDs = append(userIDs, id)
+		}
+		sort.Slice(userIDs, func(i, j int) bool {
+			return len(byUser[userIDs[i]]) > len(byUser[userIDs[j]])
+		})
+		for _, id := range userIDs {
+			series = append(series, activitySeries{group, id, group.Name + ": " + memberName(names, id), byUser[id]})
+		}
+	}
+
+	var csvOut *csv.Writer
+	if *format == "csv" {
+		csvOut = csv.NewWriter(os.Stdout)
+		defer csvOut.Flush()
+		if *by == "hour" {
+			csvOut.Write(analytics.HeatmapCSVHeader)
+		} else {
+			csvOut.Write(analytics.CountsCSVHeader)
+		}
+	}
+
+	for _, s := range series {
+		var err error
+		switch *format {
+		case "terminal":
+			fmt.Printf("%s (%d messages)\n", s.title, len(s.posts))
+			err = writeActivity(os.Stdout, s, *by, *format, loc, nil)
+			fmt.Println()
+		case "csv":
+			err = writeActivity(nil, s, *by, *format, loc, csvOut)
+		case "svg":
+			name := s.group.ID
+			if s.userID != "" {
+				name += "-" + s.userID
+			}
+			name = filepath.Join(*out, name+"-"+*by+".svg")
+			file, createErr := os.Create(name)
+			if createErr != nil {
+				log.Panic(createErr)
+			}
+			err = writeActivity(file, s, *by, *format, loc, nil)
+			file.Close()
+			fmt.Printf("Wrote %s\n", name)
+		}
+		if err != nil {
+			log.Panic(err)
+		}
+	}
+}
+
+// writeActivity writes the counts of a series in a format.
+func writeActivity(w io.Writer, s activitySeries, by, format string, loc *time.Location, csvOut *csv.Writer) error {
+	if by == "hour" {
+		h := analytics.NewHeatmap(s.posts, loc)
+		switch format {
+		case "csv":
+			return analytics.WriteHeatmapCSV(csvOut, s.group.ID, s.userID, h)
+		case "svg":
+			analytics.WriteHeatmapSVG(w, s.title, h)
+		default:
+			analytics.WriteHeatmap(w, h)
+		}
+		return nil
+	}
+
+	counts, err := analytics.CountPosts(s.posts, by, loc)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case "csv":
+		return analytics.WriteCountsCSV(csvOut, s.group.ID, s.userID, counts, by, loc)
+	case "svg":
+		analytics.WriteCountsSVG(w, s.title, counts, by, loc)
+	default:
+		analytics.WriteCounts(w, counts, by, loc)
+	}
+	return nil
+}
+
+func sessionsCommand(args []string) {
+	flags := flag.NewFlagSet("stats sessions", flag.ExitOnError)
+	groupID := flags.String("group", "", "only analyse the conversations of this group ID")
+	gap := flags.Duration("gap", analytics.DefaultSessionGap, "silence after which a new conversation starts")
+	top := flags.Int("top", 10, "number of members and pairs shown per group, 0 for all")
+	write := flags.Bool("write", true, "write the conversations into Session nodes")
+	flags.Parse(args)
+
+	driver := connectNeo4j()
+	for _, group := range statsGroups(driver, *groupID) {
+		posts, err := analytics.LoadPosts(driver, group.ID)
+		if err != nil {
+			log.Panic(err)
+		}
+		names, err := analytics.LoadMemberNames(driver, group.ID)
+		if err != nil {
+			log.Panic(err)
+		}
+		sessions := analytics.Segment(posts, *gap)
+		turns := analytics.CountTurns(sessions)
+
+		fmt.Printf("%s (%d messages in %d conversations)\n", group.Name, len(posts), len(sessions))
+		table := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(table, "Member\tStarted\tEnded")
+		for i, userID := range rankTurns(turns) {
+			if *top > 0 && i >= *top {
+				break
+			}
+			fmt.Fprintf(table, "%s\t%d\t%d\n", memberName(names, userID), turns.Started[userID], turns.Ended[userID])
+		}
+		table.Flush()
+		fmt.Println()
+
+		table = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(table, "Answering\tAnswered\tAnswers\tMedian response")
+		for i, l := range analytics.ResponseLatencies(sessions) {
+			if *top > 0 && i >= *top {
+				break
+			}
+			median := time.Duration(l.Median * float64(time.Second))
+			fmt.Fprintf(table, "%s\t%s\t%d\t%s\n", memberName(names, l.To), memberName(names, l.From), l.Answers, median)
+		}
+		table.Flush()
+		fmt.Println()
+
+		if *write {
+			err = analytics.SaveSessions(driver, group.ID, sessions, *gap)
+			if err != nil {
+				log.Panic(err)
+			}
+		}
+	}
+}
+
+// rankTurns orders members by the conversations they started and ended.
+func rankTurns(turns analytics.Turns) []string {
+	userIDs := []string{}
+	for id := range turns.Started {
+		userIDs = append(userIDs, id)
+	}
+	for id := range turns.Ended {
+		if _, ok := turns.Started[id]; !ok {
+			userIDs = append(userIDs, id)
+		}
+	}
+	sort.Slice(userIDs, func(i, j int) bool {
+		a, b := userIDs[i], userIDs[j]
+		if turns.Started[a] != turns.Started[b] {
+			return turns.Started[a] > turns.Started[b]
+		}
+		if turns.Ended[a] != turns.Ended[b] {
+			return turns.Ended[a] > turns.Ended[b]
+		}
+		return a < b
+	})
+	return userIDs
+}
+
+// groupSnapshots builds the snapshots of a group.
+func groupSnapshots(driver *database.Neo4j, groupID, period string, loc *time.Location) []analytics.Snapshot {
+	interactions, err := analytics.LoadInteractions(driver, groupID)
+	if err != nil {
+		log.Panic(err)
+	}
+	snapshots, err := analytics.Snapshots(groupID, interactions, period, loc)
+	if err != nil {
+		log.Panic(err)
+	}
+	return snapshots
+}
+
+func snapshotsCommand(args []string) {
+	flags := flag.NewFlagSet("stats snapshots", flag.ExitOnError)
+	groupID := flags.String("group", "", "only snapshot this group ID")
+	period := flags.String("window", analytics.PeriodMonth, "snapshot every day, week, month, quarter or year")
+	tz := flags.String("tz", "", "time zone of the windows, defaults to time_zone from the settings")
+	out := flags.String("out", "", "directory snapshots are written to as JSON files")
+	write := flags.Bool("write", true, "write the snapshots into Snapshot nodes and INTERACTED edges")
+	flags.Parse(args)
+
+	loc := statsLocation(readSettings(), *tz)
+	driver := connectNeo4j()
+	for _, group := range statsGroups(driver, *groupID) {
+		snapshots := groupSnapshots(driver, group.ID, *period, loc)
+		fmt.Printf("%s: %d snapshots\n", group.Name, len(snapshots))
+		for _, s := range snapshots {
+			fmt.Printf("  %s: %d members, %d edges\n", s.Label, len(s.Centralities), len(s.Edges))
+			if *out != "" {
+				err := writeSnapshotFile(filepath.Join(*out, fmt.Sprintf("%s-%s.json", s.GroupID, s.Label)), s)
+				if err != nil {
+					log.Panic(err)
+				}
+			}
+			if *write {
+				err := analytics.SaveSnapshot(driver, s)
+				if err != nil {
+					log.Panic(err)
+				}
+			}
+		}
+	}
+}
+
+func writeSnapshotFile(name string, s analytics.Snapshot) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return analytics.WriteSnapshot(file, s)
+}
+
+func readSnapshotFile(name string) analytics.Snapshot {
+	file, err := os.Open(name)
+	if err != nil {
+		log.Panic(err)
+	}
+	defer file.Close()
+	s, err := analytics.ReadSnapshot(file)
+	if err != nil {
+		log.Panic(err)
+	}
+	return s
+}
+
+func diffCommand(args []string) {
+	flags := flag.NewFlagSet("stats diff", flag.ExitOnError)
+	groupID := flags.String("group", "", "group ID whose windows are compared")
+	period := flags.String("window", analytics.PeriodMonth, "compare days, weeks, months, quarters or years")
+	from := flags.String("from", "", "label of the earlier window, e.g. 2021-03; defaults to comparing every window with the next one")
+	to := flags.String("to", "", "label of the later window")
+	tz := flags.String("tz", "", "time zone of the windows, defaults to time_zone from the settings")
+	top := flags.Int("top", 10, "number of edges and members shown, 0 for all")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: stats diff -group ID [-window month] [-from 2021-03 -to 2021-04]\n       stats diff <before.json> <after.json>\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	names := map[string]string{}
+	pairs := [][2]analytics.Snapshot{}
+	if flags.NArg() == 2 {
+		pairs = append(pairs, [2]analytics.Snapshot{readSnapshotFile(flags.Arg(0)), readSnapshotFile(flags.Arg(1))})
+	} else if *groupID != "" {
+		loc := statsLocation(readSettings(), *tz)
+		driver := connectNeo4j()
+		snapshots := groupSnapshots(driver, *groupID, *period, loc)
+		var err error
+		names, err = analytics.LoadMemberNames(driver, *groupID)
+		if err != nil {
+			log.Panic(err)
+		}
+
+		byLabel := map[string]analytics.Snapshot{}
+		for _, s := range snapshots {
+			byLabel[s.Label] = s
+		}
+		if *from != "" || *to != "" {
+			before, ok := byLabel[*from]
+			if !ok {
+				log.Panicf("no interactions during %q", *from)
+			}
+			after, ok := byLabel[*to]
+			if !ok {
+				log.Panicf("no interactions during %q", *to)
+			}
+			pairs = append(pairs, [2]analytics.Snapshot{before, after})
+		} else {
+			for i := 1; i < len(snapshots); i++ {
+				pairs = append(pairs, [2]analytics.Snapshot{snapshots[i-1], snapshots[i]})
+			}
+		}
+	} else {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	for _, pair := range pairs {
+		printDiff(pair[0], pair[1], names, *top)
+	}
+}
+
+func printDiff(before, after analytics.Snapshot, names map[string]string, top int) {
+	d := analytics.Diff(before, after)
+	fmt.Printf("%s -> %s: %d new, %d lost, %d changed edges\n", before.Label, after.Label, len(d.New), len(d.Lost), len(d.Changed))
+
+	printEdges := func(title, weight string, edges []analytics.Edge) {
+		for i, e := range edges {
+			if top > 0 && i >= top {
+				fmt.Printf("    ... %d more\n", len(edges)-top)
+				break
+			}
+			if i == 0 {
+				fmt.Printf("  %s:\n", title)
+			}
+			fmt.Printf("    %s -> %s ("+weight+")\n", memberName(names, e.From), memberName(names, e.To), e.Weight)
+		}
+	}
+	printEdges("New", "%.0f", d.New)
+	printEdges("Lost", "%.0f", d.Lost)
+	printEdges("Changed", "%+.0f", d.Changed)
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "  Member\tPageRank\tDegree\tBetweenness")
+	for i, c := range d.Centralities {
+		if top > 0 && i >= top {
+			break
+		}
+		fmt.Fprintf(table, "  %s\t%.4f -> %.4f\t%.0f -> %.0f\t%.4f -> %.4f\n", memberName(names, c.UserID),
+			c.Before.PageRank, c.After.PageRank, c.Before.Degree, c.After.Degree, c.Before.Betweenness, c.After.Betweenness)
+	}
+	table.Flush()
+	fmt.Println()
+}
+
+func overlapCommand(args []string) {
+	flags := flag.NewFlagSet("stats overlap", flag.ExitOnError)
+	minPosts := flags.Int("min-posts", 10, "messages a member must have sent to a group to be an active poster")
+	top := flags.Int("top", 10, "number of group pairs and bridges shown, 0 for all")
+	write := flags.Bool("write", true, "write the overlaps into SHARES_MEMBERS edges")
+	flags.Parse(args)
+
+	driver := connectNeo4j()
+	members, posters, err := analytics.LoadRosters(driver, *minPosts)
+	if err != nil {
+		log.Panic(err)
+	}
+	groups, err := analytics.LoadGroups(driver, "")
+	if err != nil {
+		log.Panic(err)
+	}
+	groupNames := map[string]string{}
+	for _, g := range groups {
+		groupNames[g.ID] = g.Name
+	}
+	names, err := analytics.LoadMemberNames(driver, "")
+	if err != nil {
+		log.Panic(err)
+	}
+
+	overlaps := analytics.Overlaps(members, posters)
+	table := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "Group\tGroup\tShared\tJaccard\tShared posters\tPoster Jaccard")
+	for i, o := range overlaps {
+		if *top > 0 && i >= *top {
+			break
+		}
+		fmt.Fprintf(table, "%s\t%s\t%d\t%.3f\t%d\t%.3f\n", groupNames[o.A], groupNames[o.B], o.Shared, o.Jaccard, o.SharedPosters, o.PosterJaccard)
+	}
+	table.Flush()
+	fmt.Println()
+
+	bridges := analytics.Bridges(members)
+	fmt.Printf("%d bridge members\n", len(bridges))
+	for i, b := range bridges {
+		if *top > 0 && i >= *top {
+			break
+		}
+		links := make([]string, len(b.Links))
+		for j, l := range b.Links {
+			links[j] = groupNames[l[0]] + " / " + groupNames[l[1]]
+		}
+		fmt.Printf("  %s, only link between %s\n", memberName(names, b.UserID), strings.Join(links, ", "))
+	}
+
+	if *write {
+		err = analytics.SaveOverlaps(driver, overlaps)
+		if err != nil {
+			log.Panic(err)
+		}
+	}
+}
+
+func sentimentCommand(args []string) {
+	flags := flag.NewFlagSet("stats sentiment", flag.ExitOnError)
+	groupID := flags.String("group", "", "only average the messages of this group ID")
+	by := flags.String("by", "member", "average by group, member or week")
+	rescore := flags.Bool("rescore", false, "first rate the messages saved without a sentiment")
+	tz := flags.String("tz", "", "time zone of the weeks, defaults to time_zone from the settings")
+	flags.Parse(args)
+	switch *by {
+	case "group", "member", "week":
+	default:
+		log.Panicf("unknown aggregate %q", *by)
+	}
+
+	loc := statsLocation(readSettings(), *tz)
+	driver := connectNeo4j()
+	sentiment := score.NewSentiment()
+	if *rescore {
+		count, err := score.Rescore(driver, []score.Scorer{sentiment}, 1000)
+		if err != nil {
+			log.Panic(err)
+		}
+		fmt.Printf("Rated %d messages.\n", count)
+	}
+
+	samples, err := score.LoadSamples(driver, sentiment.Property(), *groupID)
+	if err != nil {
+		log.Panic(err)
+	}
+	names := map[string]string{}
+	switch *by {
+	case "group":
+		groups, err := analytics.LoadGroups(driver, *groupID)
+		if err != nil {
+			log.Panic(err)
+		}
+		for _, g := range groups {
+			names[g.ID] = g.Name
+		}
+		printSentiment(score.AggregateBy(samples, score.ByGroup), names)
+	case "member":
+		names, err = analytics.LoadMemberNames(driver, *groupID)
+		if err != nil {
+			log.Panic(err)
+		}
+		printSentiment(score.AggregateBy(samples, score.ByMember), names)
+	case "week":
+		printSentiment(score.AggregateBy(samples, score.ByWeek(loc)), names)
+	}
+}
+
+func printSentiment(aggregates []score.Aggregate, names map[string]string) {
+	table := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "\tMessages\tMean\tPositive\tNegative")
+	for _, a := range aggregates {
+		fmt.Fprintf(table, "%s\t%d\t%+.3f\t%.0f%%\t%.0f%%\n", memberName(names, a.Key), a.Count, a.Mean, a.Positive*100, a.Negative*100)
+	}
+	table.Flush()
+}
+
+func termsCommand(args []string) {
+	flags := flag.NewFlagSet("stats terms", flag.ExitOnError)
+	groupID := flags.String("group", "", "only find the keywords of this group ID")
+	by := flags.String("by", "month", "compare the keywords of each month, member or group")
+	top := flags.Int("top", 10, "number of keywords shown and saved per month, member or group")
+	ngrams := flags.Int("ngrams", 2, "longest keywords, in words")
+	frequent := flags.Int("frequent", 0, "list the most frequent phrases of this many words instead, without writing them")
+	emoji := flags.Bool("emoji", false, "count emoji as keywords")
+	stopwords := flags.String("stopwords", "", "file of extra stopwords, one per line")
+	tz := flags.String("tz", "", "time zone of the months, defaults to time_zone from the settings")
+	write := flags.Bool("write", true, "write the keywords into Term nodes")
+	flags.Parse(args)
+	switch *by {
+	case "month", "member", "group":
+	default:
+		log.Panicf("unknown document %q", *by)
+	}
+
+	settings := readSettings()
+	loc := statsLocation(settings, *tz)
+	tokenizer := keywords.NewTokenizer()
+	tokenizer.MaxN = *ngrams
+	if *frequent > tokenizer.MaxN {
+		tokenizer.MaxN = *frequent
+	}
+	tokenizer.KeepEmoji = *emoji
+	tokenizer.AddStopwords(settings.Stopwords...)
+	if *stopwords != "" {
+		contents, err := ioutil.ReadFile(*stopwords)
+		if err != nil {
+			log.Panic(err)
+		}
+		tokenizer.AddStopwords(strings.Fields(string(contents))...)
+	}
+
+	// Frequent phrases are plain counts, which have no place among the
+	// keywords saved in the graph.
+	find := func(corpus *keywords.Corpus, document string) []keywords.Keyword {
+		if *frequent > 0 {
+			return corpus.Frequent(document, *frequent, *top)
+		}
+		return corpus.Keywords(document, *top)
+	}
+	save := *write && *frequent <= 0
+
+	driver := connectNeo4j()
+	if *by == "group" {
+		texts, err := keywords.LoadTexts(driver, *groupID)
+		if err != nil {
+			log.Panic(err)
+		}
+		corpus := keywords.NewCorpus(tokenizer)
+		for _, t := range texts {
+			corpus.Add(t.GroupID, t.Text)
+		}
+		for _, group := range statsGroups(driver, *groupID) {
+			found := find(corpus, group.ID)
+			printKeywords(group.Name, found)
+			if save {
+				err = keywords.SaveGroupKeywords(driver, group.ID, analytics.PeriodAll, 0, "all time", found)
+				if err != nil {
+					log.Panic(err)
+				}
+			}
+		}
+		return
+	}
+
+	for _, group := range statsGroups(driver, *groupID) {
+		texts, err := keywords.LoadTexts(driver, group.ID)
+		if err != nil {
+			log.Panic(err)
+		}
+		corpus := keywords.NewCorpus(tokenizer)
+		windows := map[string]analytics.Window{}
+		for _, t := range texts {
+			switch *by {
+			case "month":
+				w, _ := analytics.WindowOf(analytics.PeriodMonth, t.At, loc)
+				label := w.Label(analytics.PeriodMonth, loc)
+				windows[label] = w
+				corpus.Add(label, t.Text)
+			case "member":
+				corpus.Add(t.UserID, t.Text)
+			}
+		}
+
+		names := map[string]string{}
+		if *by == "member" {
+			names, err = analytics.LoadMemberNames(driver, group.ID)
+			if err != nil {
+				log.Panic(err)
+			}
+		}
+		fmt.Printf("%s\n", group.Name)
+		for _, document := range corpus.Documents() {
+			found := find(corpus, document)
+			printKeywords("  "+memberName(names, document), found)
+			if !save {
+				continue
+			}
+			if *by == "month" {
+				err = keywords.SaveGroupKeywords(driver, group.ID, analytics.PeriodMonth, windows[document].Start, document, found)
+			} else {
+				err = keywords.SaveMemberKeywords(driver, group.ID, document, found)
+			}
+			if err != nil {
+				log.Panic(err)
+			}
+		}
+		fmt.Println()
+	}
+}
+
+func printKeywords(title string, found []keywords.Keyword) {
+	terms := make([]string, len(found))
+	for i, k := range found {
+		terms[i] = fmt.Sprintf("%s (%d)", k.Term, k.Count)
+	}
+	fmt.Printf("%s: %s\n", title, strings.Join(terms, ", "))
+}
+
+func linksCommand(args []string) {
+	flags := flag.NewFlagSet("stats links", flag.ExitOnError)
+	groupID := flags.String("group", "", "only rank the domains of this group ID")
+	top := flags.Int("top", 10, "number of domains shown per group, 0 for all")
+	relink := flags.Bool("relink", false, "first extract the links of messages saved without them")
+	flags.Parse(args)
+
+	driver := connectNeo4j()
+	if *relink {
+		count, err := groupme.Relink(driver, 1000)
+		if err != nil {
+			log.Panic(err)
+		}
+		fmt.Printf("Read %d messages.\n", count)
+	}
+
+	shares, err := analytics.LoadShares(driver, *groupID)
+	if err != nil {
+		log.Panic(err)
+	}
+	domains := analytics.Domains(shares)
+	for _, group := range statsGroups(driver, *groupID) {
+		counts := domains[group.ID]
+		if len(counts) == 0 {
+			continue
+		}
+		fmt.Printf("%s\n", group.Name)
+		table := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(table, "Domain\tShares\tLinks\tSharers")
+		for i, c := range counts {
+			if *top > 0 && i >= *top {
+				break
+			}
+			fmt.Fprintf(table, "%s\t%d\t%d\t%d\n", c.Domain, c.Shares, c.Links, c.Sharers)
+		}
+		table.Flush()
+		fmt.Println()
+	}
+}